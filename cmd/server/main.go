@@ -18,7 +18,10 @@ func main() {
 	defer cancel()
 
 	// Create and configure server
-	fs := server.NewFetchServer(cfg)
+	fs, err := server.NewFetchServer(cfg)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Start server
 	serverErrCh := make(chan error, 1)
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskSuffix marks the files a DiskStore owns in its directory
+const diskSuffix = ".response"
+
+// DiskStore keeps responses as files in a directory within a byte budget,
+// removing the least recently used files once it is exceeded. Responses
+// survive restarts of the server.
+type DiskStore struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	used     int64
+	now      func() time.Time
+}
+
+// diskEntry is the serialized form of a stored response
+type diskEntry struct {
+	Key      string
+	Response *Response
+}
+
+// NewDiskStore creates a disk store in dir, creating the directory if needed
+// and accounting for the responses already stored there
+func NewDiskStore(dir string, maxBytes int64) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	s := &DiskStore{dir: dir, maxBytes: maxBytes, now: time.Now}
+
+	files, err := s.files()
+	if err != nil {
+		return nil, fmt.Errorf("reading cache directory: %w", err)
+	}
+	for _, file := range files {
+		s.used += file.size
+	}
+	s.mu.Lock()
+	s.evictLocked()
+	s.mu.Unlock()
+	return s, nil
+}
+
+// Get returns the response stored under key, marking it as recently used
+func (s *DiskStore) Get(key string) (*Response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var entry diskEntry
+	if err := gob.NewDecoder(file).Decode(&entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	now := s.now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		log.Printf("Failed to touch cached response %s: %v", path, err)
+	}
+	return entry.Response, true
+}
+
+// Set stores a response under key, replacing the file atomically
+func (s *DiskStore) Set(key string, resp *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	temp, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		log.Printf("Failed to store response for %s: %v", key, err)
+		return
+	}
+	defer os.Remove(temp.Name())
+
+	err = gob.NewEncoder(temp).Encode(diskEntry{Key: key, Response: resp})
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Printf("Failed to store response for %s: %v", key, err)
+		return
+	}
+
+	info, err := os.Stat(temp.Name())
+	if err != nil || info.Size() > s.maxBytes {
+		return
+	}
+	path := s.path(key)
+	s.used -= fileSize(path)
+	if err := os.Rename(temp.Name(), path); err != nil {
+		log.Printf("Failed to store response for %s: %v", key, err)
+		return
+	}
+	s.used += info.Size()
+	s.evictLocked()
+}
+
+// Delete removes the response stored under key
+func (s *DiskStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	size := fileSize(path)
+	if os.Remove(path) == nil {
+		s.used -= size
+	}
+}
+
+// diskFile is a stored response file with its size and last use
+type diskFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// files lists the stored response files
+func (s *DiskStore) files() ([]diskFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []diskFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), diskSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, diskFile{path: filepath.Join(s.dir, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// evictLocked removes the least recently used files until the store is within
+// budget; the caller must hold the lock
+func (s *DiskStore) evictLocked() {
+	if s.used <= s.maxBytes {
+		return
+	}
+	files, err := s.files()
+	if err != nil {
+		log.Printf("Failed to list cached responses: %v", err)
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	for _, file := range files {
+		if s.used <= s.maxBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			s.used -= file.size
+		}
+	}
+}
+
+// path returns the file holding the response stored under key
+func (s *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+diskSuffix)
+}
+
+// fileSize returns the size of a file, or zero if it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := NewDiskStore(dir, 1024*1024)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	store.Set("https://example.com/", &Response{
+		URL:          "https://example.com/",
+		StatusCode:   http.StatusOK,
+		Header:       http.Header{"Etag": {`"v1"`}},
+		Body:         []byte("<p>Hello</p>"),
+		ResponseTime: now,
+	})
+
+	// A new store over the same directory finds the response
+	reopened, err := NewDiskStore(dir, 1024*1024)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp, ok := reopened.Get("https://example.com/")
+	if !ok || string(resp.Body) != "<p>Hello</p>" || resp.Header.Get("ETag") != `"v1"` || !resp.ResponseTime.Equal(now) {
+		t.Fatalf("expected the stored response, got %+v", resp)
+	}
+	if reopened.used != store.used || reopened.used == 0 {
+		t.Errorf("expected %d bytes to be accounted for, got %d", store.used, reopened.used)
+	}
+
+	reopened.Delete("https://example.com/")
+	if _, ok := reopened.Get("https://example.com/"); ok || reopened.used != 0 {
+		t.Errorf("expected the response to be deleted, %d bytes left", reopened.used)
+	}
+}
+
+func TestDiskStoreEviction(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	store, err := NewDiskStore(dir, 1024*1024)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	store.now = func() time.Time { return now }
+
+	body := make([]byte, 1000)
+	store.Set("a", &Response{Body: body})
+	store.Set("b", &Response{Body: body})
+	size := store.used / 2
+
+	// Use a and age b, then shrink the budget to two responses
+	os.Chtimes(store.path("b"), now.Add(-time.Hour), now.Add(-time.Hour))
+	os.Chtimes(store.path("a"), now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+	store.Get("a")
+	store.maxBytes = 2 * size
+
+	store.Set("c", &Response{Body: body})
+	if _, ok := store.Get("b"); ok {
+		t.Error("expected b, the least recently used, to be evicted")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+	if store.used > store.maxBytes {
+		t.Errorf("expected the store to be within budget, using %d bytes", store.used)
+	}
+
+	// Responses larger than the whole budget are not stored
+	store.Set("big", &Response{Body: make([]byte, 10*size)})
+	if _, ok := store.Get("big"); ok {
+		t.Error("expected an oversized response not to be stored")
+	}
+}
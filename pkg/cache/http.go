@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHeuristicLifetime caps the freshness guessed from Last-Modified
+const maxHeuristicLifetime = 24 * time.Hour
+
+// Response is a stored HTTP response with the times needed to compute its
+// age, following RFC 9111 as a private cache
+type Response struct {
+	// URL is the URL the response was served from, after following redirects
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Truncated reports whether the body was cut off at the fetcher's size limit
+	Truncated bool
+	// RequestTime and ResponseTime bracket the request that produced or last
+	// revalidated the response
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// Storable reports whether a response may be stored: a 200 OK that neither
+// forbids storage nor varies on everything
+func Storable(resp *http.Response) bool {
+	if resp.Request == nil || resp.Request.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if _, ok := cacheControl(resp.Header)["no-store"]; ok {
+		return false
+	}
+	return strings.TrimSpace(resp.Header.Get("Vary")) != "*"
+}
+
+// Fresh reports whether the response may be served without revalidation
+func (r *Response) Fresh(now time.Time) bool {
+	if _, ok := cacheControl(r.Header)["no-cache"]; ok {
+		return false
+	}
+	return r.FreshnessLifetime() > r.Age(now)
+}
+
+// FreshnessLifetime returns how long the response stays fresh after it was
+// generated, from max-age, Expires or, lacking both, a tenth of the time since
+// Last-Modified
+func (r *Response) FreshnessLifetime() time.Duration {
+	directives := cacheControl(r.Header)
+	if value, ok := directives["max-age"]; ok {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+
+	date := r.date()
+	if expires := r.Header.Get("Expires"); expires != "" {
+		// An invalid Expires, such as "0", means already expired
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return max(t.Sub(date), 0)
+	}
+
+	if lastModified, err := http.ParseTime(r.Header.Get("Last-Modified")); err == nil && lastModified.Before(date) {
+		return min(date.Sub(lastModified)/10, maxHeuristicLifetime)
+	}
+	return 0
+}
+
+// Age returns the current age of the response, as computed in RFC 9111
+// section 4.2.3
+func (r *Response) Age(now time.Time) time.Duration {
+	apparentAge := max(r.ResponseTime.Sub(r.date()), 0)
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get("Age")), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	correctedAge := ageValue + r.ResponseTime.Sub(r.RequestTime)
+
+	return max(apparentAge, correctedAge) + now.Sub(r.ResponseTime)
+}
+
+// SetConditional adds the response's validators to a request revalidating it
+func (r *Response) SetConditional(header http.Header) {
+	if etag := r.Header.Get("ETag"); etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified := r.Header.Get("Last-Modified"); lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// Revalidated returns a copy of the response updated with the header fields of
+// a 304 Not Modified answer to a revalidation made at the given times
+func (r *Response) Revalidated(notModified http.Header, requestTime, responseTime time.Time) *Response {
+	updated := *r
+	updated.Header = r.Header.Clone()
+	for key, values := range notModified {
+		switch key {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		updated.Header[key] = values
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+// Size approximates the memory the response occupies, in bytes
+func (r *Response) Size() int64 {
+	size := len(r.URL) + len(r.Body)
+	for key, values := range r.Header {
+		for _, value := range values {
+			size += len(key) + len(value)
+		}
+	}
+	return int64(size)
+}
+
+// date returns the Date header, or the response time if it is missing or invalid
+func (r *Response) date() time.Time {
+	if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		return date
+	}
+	return r.ResponseTime
+}
+
+// cacheControl parses the Cache-Control directives of a header, lowercasing
+// their names and unquoting their values
+func cacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, line := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
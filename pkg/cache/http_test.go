@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newResponse returns a response received at now with the given header fields
+func newResponse(now time.Time, fields ...string) *Response {
+	header := http.Header{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		header.Add(fields[i], fields[i+1])
+	}
+	return &Response{StatusCode: http.StatusOK, Header: header, RequestTime: now, ResponseTime: now}
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		fields   []string
+		expected time.Duration
+	}{
+		{"max-age", []string{"Cache-Control", "public, max-age=300"}, 5 * time.Minute},
+		{"max-age overrides Expires", []string{"Cache-Control", "max-age=60", "Expires", now.Add(time.Hour).Format(http.TimeFormat)}, time.Minute},
+		{"invalid max-age", []string{"Cache-Control", "max-age=soon"}, 0},
+		{"Expires relative to Date", []string{"Date", date, "Expires", now.Add(time.Hour).Format(http.TimeFormat)}, time.Hour},
+		{"invalid Expires", []string{"Expires", "0"}, 0},
+		{"heuristic", []string{"Date", date, "Last-Modified", now.Add(-10 * time.Hour).Format(http.TimeFormat)}, time.Hour},
+		{"capped heuristic", []string{"Date", date, "Last-Modified", now.AddDate(-1, 0, 0).Format(http.TimeFormat)}, 24 * time.Hour},
+		{"no information", []string{"Date", date}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newResponse(now, tt.fields...).FreshnessLifetime(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFresh(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	resp := newResponse(now, "Cache-Control", "max-age=60", "Age", "30")
+	if !resp.Fresh(now.Add(20 * time.Second)) {
+		t.Error("expected the response to be fresh 50s into its 60s lifetime")
+	}
+	if resp.Fresh(now.Add(40 * time.Second)) {
+		t.Error("expected the Age header to count against the lifetime")
+	}
+
+	// Date in the past makes the response older than its receipt time suggests
+	resp = newResponse(now, "Cache-Control", "max-age=60", "Date", now.Add(-time.Minute).Format(http.TimeFormat))
+	if resp.Fresh(now) {
+		t.Error("expected a response generated a minute ago to be stale")
+	}
+
+	resp = newResponse(now, "Cache-Control", `no-cache="Set-Cookie", max-age=60`)
+	if resp.Fresh(now) {
+		t.Error("expected no-cache to require revalidation")
+	}
+}
+
+func TestStorable(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	head, _ := http.NewRequest(http.MethodHead, "https://example.com/", nil)
+
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		expected bool
+	}{
+		{"plain 200", &http.Response{Request: get, StatusCode: 200, Header: http.Header{}}, true},
+		{"no-store", &http.Response{Request: get, StatusCode: 200, Header: http.Header{"Cache-Control": {"No-Store"}}}, false},
+		{"vary on everything", &http.Response{Request: get, StatusCode: 200, Header: http.Header{"Vary": {"*"}}}, false},
+		{"not found", &http.Response{Request: get, StatusCode: 404, Header: http.Header{}}, false},
+		{"head request", &http.Response{Request: head, StatusCode: 200, Header: http.Header{}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Storable(tt.resp); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRevalidated(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resp := newResponse(now, "ETag", `"v1"`, "Content-Type", "text/html", "Cache-Control", "max-age=60")
+	resp.Body = []byte("body")
+
+	conditional := http.Header{}
+	resp.SetConditional(conditional)
+	if conditional.Get("If-None-Match") != `"v1"` || conditional.Get("If-Modified-Since") != "" {
+		t.Errorf("unexpected conditional headers %v", conditional)
+	}
+
+	later := now.Add(time.Hour)
+	updated := resp.Revalidated(http.Header{"Cache-Control": {"max-age=120"}, "Content-Length": {"0"}}, later, later)
+	if updated.Header.Get("Cache-Control") != "max-age=120" || updated.Header.Get("Content-Type") != "text/html" ||
+		updated.Header.Get("Content-Length") != "" || string(updated.Body) != "body" || !updated.Fresh(later) {
+		t.Errorf("unexpected revalidated response %+v", updated)
+	}
+	if resp.Header.Get("Cache-Control") != "max-age=60" || !resp.ResponseTime.Equal(now) {
+		t.Error("expected the stored response to be left unchanged")
+	}
+}
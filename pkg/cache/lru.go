@@ -0,0 +1,123 @@
+// Package cache provides the in-memory and on-disk caches shared by the fetch tools.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded, least recently used cache safe for concurrent use.
+// Each entry has a cost, such as its size in bytes, and the least recently
+// used entries are evicted once the total cost exceeds the budget. Entries may
+// also expire.
+type LRU[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxCost int64
+	cost    func(V) int64
+	used    int64
+	order   *list.List
+	items   map[K]*list.Element
+	now     func() time.Time
+}
+
+// lruEntry is a cached value with its bookkeeping
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	cost    int64
+	expires time.Time
+}
+
+// NewLRU creates a cache holding entries up to a total cost of maxCost, as
+// measured by cost. A nil cost counts every entry as one, bounding the number
+// of entries instead.
+func NewLRU[K comparable, V any](maxCost int64, cost func(V) int64) *LRU[K, V] {
+	if cost == nil {
+		cost = func(V) int64 { return 1 }
+	}
+	return &LRU[K, V]{
+		maxCost: maxCost,
+		cost:    cost,
+		order:   list.New(),
+		items:   make(map[K]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value cached under key, marking it as recently used.
+// Expired entries are removed and reported as missing.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.removeLocked(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Add caches a value under key for ttl, replacing any previous value, and
+// evicts least recently used entries to stay within budget. A ttl of zero or
+// less never expires. Values costing more than the whole budget are not cached.
+func (c *LRU[K, V]) Add(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeLocked(elem)
+	}
+
+	entry := &lruEntry[K, V]{key: key, value: value, cost: c.cost(value)}
+	if entry.cost > c.maxCost {
+		return
+	}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	c.items[key] = c.order.PushFront(entry)
+	c.used += entry.cost
+
+	for c.used > c.maxCost {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// Remove drops the value cached under key, if any
+func (c *LRU[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeLocked(elem)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet removed
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Cost returns the total cost of the cached entries
+func (c *LRU[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// removeLocked unlinks an entry; the caller must hold the lock
+func (c *LRU[K, V]) removeLocked(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry[K, V])
+	delete(c.items, entry.key)
+	c.used -= entry.cost
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, string](10, func(v string) int64 { return int64(len(v)) })
+
+	c.Add("a", "aaaa", 0)
+	c.Add("b", "bbbb", 0)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+
+	// Adding c exceeds the budget and evicts b, the least recently used entry
+	c.Add("c", "cccc", 0)
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("expected a to survive")
+	}
+	if c.Len() != 2 || c.Cost() != 8 {
+		t.Errorf("expected 2 entries costing 8, got %d costing %d", c.Len(), c.Cost())
+	}
+}
+
+func TestLRUReplaceAndRemove(t *testing.T) {
+	c := NewLRU[string, int](3, nil)
+
+	c.Add("a", 1, 0)
+	c.Add("a", 2, 0)
+	if v, ok := c.Get("a"); !ok || v != 2 || c.Len() != 1 {
+		t.Errorf("expected a single replaced entry, got %d, %v, %d entries", v, ok, c.Len())
+	}
+
+	c.Remove("a")
+	if _, ok := c.Get("a"); ok || c.Cost() != 0 {
+		t.Error("expected a to be removed")
+	}
+}
+
+func TestLRUSkipsOversizedValues(t *testing.T) {
+	c := NewLRU[string, string](3, func(v string) int64 { return int64(len(v)) })
+
+	c.Add("small", "ab", 0)
+	c.Add("big", "abcd", 0)
+	if _, ok := c.Get("big"); ok {
+		t.Error("expected a value over the budget not to be cached")
+	}
+	if _, ok := c.Get("small"); !ok {
+		t.Error("expected the existing entry to be kept")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string, int](10, nil)
+	c.now = func() time.Time { return now }
+
+	c.Add("short", 1, time.Minute)
+	c.Add("forever", 2, 0)
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("short"); ok {
+		t.Error("expected the entry to expire")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("expected an entry without ttl to stay")
+	}
+	if c.Len() != 1 {
+		t.Errorf("expected the expired entry to be removed, got %d entries", c.Len())
+	}
+}
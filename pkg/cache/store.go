@@ -0,0 +1,71 @@
+package cache
+
+// Store holds HTTP responses by key. Implementations must be safe for
+// concurrent use and must not modify a response once it is stored.
+type Store interface {
+	Get(key string) (*Response, bool)
+	Set(key string, resp *Response)
+	Delete(key string)
+}
+
+// MemoryStore keeps responses in memory within a byte budget, evicting the
+// least recently used
+type MemoryStore struct {
+	lru *LRU[string, *Response]
+}
+
+// NewMemoryStore creates a memory store holding up to maxBytes of responses
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	return &MemoryStore{lru: NewLRU[string](maxBytes, (*Response).Size)}
+}
+
+// Get returns the response stored under key
+func (s *MemoryStore) Get(key string) (*Response, bool) {
+	return s.lru.Get(key)
+}
+
+// Set stores a response under key; stale responses are kept for revalidation
+func (s *MemoryStore) Set(key string, resp *Response) {
+	s.lru.Add(key, resp, 0)
+}
+
+// Delete removes the response stored under key
+func (s *MemoryStore) Delete(key string) {
+	s.lru.Remove(key)
+}
+
+// TieredStore serves responses from a fast store, falling back to a slower,
+// larger one such as a DiskStore and promoting what it finds there
+type TieredStore struct {
+	fast Store
+	slow Store
+}
+
+// NewTieredStore layers a fast store over a slow one; responses are written to both
+func NewTieredStore(fast, slow Store) *TieredStore {
+	return &TieredStore{fast: fast, slow: slow}
+}
+
+// Get returns the response stored under key in either store
+func (s *TieredStore) Get(key string) (*Response, bool) {
+	if resp, ok := s.fast.Get(key); ok {
+		return resp, true
+	}
+	resp, ok := s.slow.Get(key)
+	if ok {
+		s.fast.Set(key, resp)
+	}
+	return resp, ok
+}
+
+// Set stores a response under key in both stores
+func (s *TieredStore) Set(key string, resp *Response) {
+	s.fast.Set(key, resp)
+	s.slow.Set(key, resp)
+}
+
+// Delete removes the response stored under key from both stores
+func (s *TieredStore) Delete(key string) {
+	s.fast.Delete(key)
+	s.slow.Delete(key)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(10)
+
+	store.Set("a", &Response{Body: []byte("12345")})
+	store.Set("b", &Response{Body: []byte("12345")})
+	if _, ok := store.Get("a"); !ok {
+		t.Fatal("expected a to be stored")
+	}
+
+	// Storing c goes over budget and evicts b, the least recently used
+	store.Set("c", &Response{Body: []byte("12")})
+	if _, ok := store.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Error("expected a to be kept")
+	}
+
+	store.Delete("a")
+	if _, ok := store.Get("a"); ok {
+		t.Error("expected a to be deleted")
+	}
+}
+
+func TestTieredStore(t *testing.T) {
+	fast := NewMemoryStore(1024)
+	slow := NewMemoryStore(1024)
+	store := NewTieredStore(fast, slow)
+
+	store.Set("a", &Response{StatusCode: http.StatusOK})
+	if _, ok := slow.Get("a"); !ok {
+		t.Error("expected responses to be written to the slow store")
+	}
+
+	slow.Set("b", &Response{StatusCode: http.StatusOK})
+	if _, ok := store.Get("b"); !ok {
+		t.Fatal("expected the slow store to be consulted")
+	}
+	if _, ok := fast.Get("b"); !ok {
+		t.Error("expected b to be promoted to the fast store")
+	}
+
+	store.Delete("b")
+	if _, ok := slow.Get("b"); ok {
+		t.Error("expected b to be deleted from both stores")
+	}
+}
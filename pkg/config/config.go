@@ -3,8 +3,14 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stackloklabs/gofetch/pkg/netguard"
 )
 
 // Constants
@@ -12,6 +18,22 @@ const (
 	ServerName    = "fetch-server"
 	ServerVersion = "1.0.0"
 	DefaultUA     = "Mozilla/5.0 (compatible; MCPFetchBot/1.0)"
+
+	// DefaultMaxWorkers is the default number of URLs fetched in parallel by batch tools
+	DefaultMaxWorkers = 4
+
+	// DefaultHTTPCacheSize is the default byte budget of the in-memory HTTP cache
+	DefaultHTTPCacheSize = 64 * 1024 * 1024
+	// DefaultHTTPCacheDiskSize is the default byte budget of the on-disk HTTP cache
+	DefaultHTTPCacheDiskSize = 1024 * 1024 * 1024
+	// DefaultContentCacheSize is the default byte budget of the processed content cache
+	DefaultContentCacheSize = 32 * 1024 * 1024
+)
+
+// Oversize policies
+const (
+	OversizeError    = "error"
+	OversizeTruncate = "truncate"
 )
 
 // Transport types
@@ -27,6 +49,26 @@ type Config struct {
 	IgnoreRobots bool
 	ProxyURL     string
 	Transport    string
+	// AllowedCIDRs lists private or internal networks that fetches may reach.
+	// All non-public addresses are blocked unless they fall within one of these.
+	AllowedCIDRs []string
+	// RobotsCacheTTL is how long robots.txt is cached when the response carries no caching directives
+	RobotsCacheTTL time.Duration
+	// MinHostInterval is the minimum time between requests to the same host
+	MinHostInterval time.Duration
+	// MaxBodySize is the largest response body, in bytes after decompression, that is read
+	MaxBodySize int64
+	// OversizePolicy is either OversizeError or OversizeTruncate
+	OversizePolicy string
+	// MaxWorkers is the most URLs a batch tool call fetches at the same time
+	MaxWorkers int
+	// HTTPCacheSize is the byte budget of the in-memory HTTP cache; zero disables it
+	HTTPCacheSize int64
+	// HTTPCacheDir, if set, keeps HTTP responses on disk as well, within HTTPCacheDiskSize bytes
+	HTTPCacheDir      string
+	HTTPCacheDiskSize int64
+	// ContentCacheSize is the byte budget of the processed content cache; zero disables it
+	ContentCacheSize int64
 }
 
 var transport string
@@ -56,7 +98,27 @@ func parseConfig(config *Config) {
 	flag.IntVar(&port, "port", 8080, "Port number for HTTP-based transports")
 	flag.StringVar(&config.UserAgent, "user-agent", "", "Custom User-Agent string")
 	flag.BoolVar(&config.IgnoreRobots, "ignore-robots-txt", false, "Ignore robots.txt rules")
-	flag.StringVar(&config.ProxyURL, "proxy-url", "", "Proxy URL for requests")
+	flag.Func("proxy-url", "Proxy URL for requests", func(value string) error {
+		return config.setProxyURL(value)
+	})
+	flag.Func("allow-cidr", "Comma-separated CIDRs of internal networks that may be fetched (repeatable)", func(value string) error {
+		return config.addAllowedCIDRs(value)
+	})
+	flag.DurationVar(&config.RobotsCacheTTL, "robots-cache-ttl", time.Hour, "How long to cache robots.txt per host")
+	flag.DurationVar(&config.MinHostInterval, "min-host-interval", 0,
+		"Minimum time between requests to the same host when robots.txt sets no longer Crawl-delay")
+	flag.Int64Var(&config.MaxBodySize, "max-body-size", 10*1024*1024,
+		"Maximum response body size in bytes, after decompression, 0 to disable the limit")
+	flag.StringVar(&config.OversizePolicy, "oversize-policy", OversizeError,
+		"What to do with responses over the size limit: error or truncate")
+	flag.IntVar(&config.MaxWorkers, "max-workers", DefaultMaxWorkers, "Maximum number of URLs a batch tool call fetches in parallel")
+	flag.Int64Var(&config.HTTPCacheSize, "http-cache-size", DefaultHTTPCacheSize,
+		"Byte budget of the in-memory HTTP response cache, 0 to disable it")
+	flag.StringVar(&config.HTTPCacheDir, "http-cache-dir", "", "Directory to also keep HTTP responses in across restarts")
+	flag.Int64Var(&config.HTTPCacheDiskSize, "http-cache-disk-size", DefaultHTTPCacheDiskSize,
+		"Byte budget of the on-disk HTTP response cache")
+	flag.Int64Var(&config.ContentCacheSize, "content-cache-size", DefaultContentCacheSize,
+		"Byte budget of the cache of processed pages, 0 to disable it")
 	flag.Parse()
 
 	if t, ok := os.LookupEnv("TRANSPORT"); ok {
@@ -68,3 +130,41 @@ func parseConfig(config *Config) {
 		}
 	}
 }
+
+// addAllowedCIDRs appends the CIDRs of an --allow-cidr value, rejecting
+// malformed ones so that a typo fails at startup instead of being ignored
+func (c *Config) addAllowedCIDRs(value string) error {
+	cidrs := splitList(value)
+	if _, err := netguard.ParseCIDRs(cidrs); err != nil {
+		return err
+	}
+	c.AllowedCIDRs = append(c.AllowedCIDRs, cidrs...)
+	return nil
+}
+
+// setProxyURL sets the --proxy-url value, rejecting one that is not an
+// absolute URL so that a typo fails at startup instead of bypassing the proxy
+func (c *Config) setProxyURL(value string) error {
+	if value != "" {
+		proxyURL, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q: scheme and host are required", value)
+		}
+	}
+	c.ProxyURL = value
+	return nil
+}
+
+// splitList splits a comma-separated flag value into its non-empty, trimmed elements
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
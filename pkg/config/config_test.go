@@ -82,6 +82,16 @@ func TestParseFlags(t *testing.T) {
 	if config.UserAgent != DefaultUA {
 		t.Errorf("expected default user agent %q, got %q", DefaultUA, config.UserAgent)
 	}
+	if config.MaxWorkers != DefaultMaxWorkers {
+		t.Errorf("expected %d workers by default, got %d", DefaultMaxWorkers, config.MaxWorkers)
+	}
+	if config.HTTPCacheSize != DefaultHTTPCacheSize || config.HTTPCacheDir != "" {
+		t.Errorf("expected an in-memory HTTP cache of %d bytes by default, got %d bytes in %q",
+			DefaultHTTPCacheSize, config.HTTPCacheSize, config.HTTPCacheDir)
+	}
+	if config.ContentCacheSize != DefaultContentCacheSize {
+		t.Errorf("expected a content cache of %d bytes by default, got %d", DefaultContentCacheSize, config.ContentCacheSize)
+	}
 }
 
 func TestTransportValidation(t *testing.T) {
@@ -112,3 +122,48 @@ func BenchmarkConfigCreation(b *testing.B) {
 		}
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	items := splitList(" 10.0.0.0/8, ,192.168.1.10,")
+
+	expected := []string{"10.0.0.0/8", "192.168.1.10"}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, got %d", len(expected), len(items))
+	}
+	for i, item := range items {
+		if item != expected[i] {
+			t.Errorf("expected item %q, got %q", expected[i], item)
+		}
+	}
+}
+
+func TestAddAllowedCIDRs(t *testing.T) {
+	var config Config
+
+	if err := config.addAllowedCIDRs("10.0.0.0/8, 192.168.1.10"); err != nil {
+		t.Fatalf("expected valid CIDRs to be accepted, got %v", err)
+	}
+	if err := config.addAllowedCIDRs("10.0.0.0/33"); err == nil {
+		t.Error("expected a malformed CIDR to be rejected")
+	}
+	if len(config.AllowedCIDRs) != 2 {
+		t.Errorf("expected only the valid CIDRs to be kept, got %v", config.AllowedCIDRs)
+	}
+}
+
+func TestSetProxyURL(t *testing.T) {
+	var config Config
+
+	for _, value := range []string{"proxy.example.com:8080", "http//proxy.example.com", "http://%zz"} {
+		if err := config.setProxyURL(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+	if config.ProxyURL != "" {
+		t.Errorf("expected invalid proxy URLs not to be kept, got %q", config.ProxyURL)
+	}
+
+	if err := config.setProxyURL("http://proxy.example.com:8080"); err != nil || config.ProxyURL != "http://proxy.example.com:8080" {
+		t.Errorf("expected a valid proxy URL to be kept, got %q, %v", config.ProxyURL, err)
+	}
+}
@@ -0,0 +1,162 @@
+package fetcher
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// minSniffConfidence is the lowest chardet confidence, out of 100, that is trusted
+const minSniffConfidence = 50
+
+// metaPrescanBytes is how far into an HTML page a <meta> charset declaration is looked for
+const metaPrescanBytes = 1024
+
+// utf8BOM is the byte order mark some UTF-8 documents start with
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeBody transcodes a textual response body to UTF-8. The encoding is taken
+// from a byte order mark, the Content-Type charset or an HTML <meta> declaration,
+// in that order; if none is present the body is sniffed. It returns the decoded
+// body and the name of the detected charset. Non-textual bodies, including
+// those without a Content-Type that do not sniff as text, are returned
+// unchanged with an empty charset.
+func decodeBody(body []byte, contentType string) ([]byte, string) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		// Only the sniffed media type is used: its charset is a guess
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	if !isTextual(mediaType) {
+		return body, ""
+	}
+
+	enc, name := declaredEncoding(body, contentType, mediaType)
+	if enc == nil {
+		enc, name = sniffEncoding(body, mediaType)
+	}
+
+	if name == "utf-8" {
+		return bytes.TrimPrefix(body, utf8BOM), name
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, name
+	}
+	return decoded, name
+}
+
+// declaredEncoding returns the encoding a byte order mark, the Content-Type
+// charset or, for HTML, a <meta> declaration names, or nil if there is none
+func declaredEncoding(body []byte, contentType, mediaType string) (encoding.Encoding, string) {
+	// DetermineEncoding is only certain of a byte order mark or a Content-Type
+	// charset; its meta prescan and fallback guess are not told apart
+	if enc, name, certain := charset.DetermineEncoding(body, contentType); certain {
+		return enc, name
+	}
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ""
+	}
+	label := metaCharset(body)
+	if label == "" {
+		return nil, ""
+	}
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		return nil, ""
+	}
+	// A page whose meta tag could be read is not UTF-16, whatever it declares
+	if strings.HasPrefix(name, "utf-16") {
+		return encoding.Nop, "utf-8"
+	}
+	return enc, name
+}
+
+// metaCharset returns the charset label an HTML page declares with a <meta>
+// tag in its first 1024 bytes, as the HTML encoding prescan looks for it
+func metaCharset(body []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body[:min(len(body), metaPrescanBytes)]))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+			if label := metaTagCharset(tokenizer); label != "" {
+				return label
+			}
+		}
+	}
+}
+
+// metaTagCharset returns the charset of the <meta> tag the tokenizer is on,
+// from its charset attribute or a Content-Type http-equiv declaration
+func metaTagCharset(tokenizer *html.Tokenizer) string {
+	attrs := make(map[string]string)
+	for more := true; more; {
+		var key, value []byte
+		key, value, more = tokenizer.TagAttr()
+		attrs[string(key)] = string(value)
+	}
+
+	if label := strings.TrimSpace(attrs["charset"]); label != "" {
+		return label
+	}
+	if strings.EqualFold(attrs["http-equiv"], "content-type") {
+		if _, params, err := mime.ParseMediaType(attrs["content"]); err == nil {
+			return strings.TrimSpace(params["charset"])
+		}
+	}
+	return ""
+}
+
+// sniffEncoding guesses the encoding of an undeclared body, preferring UTF-8
+// when the whole body is valid UTF-8 and falling back to Windows-1252
+func sniffEncoding(body []byte, mediaType string) (encoding.Encoding, string) {
+	if utf8.Valid(body) {
+		return encoding.Nop, "utf-8"
+	}
+
+	detector := chardet.NewTextDetector()
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		detector = chardet.NewHtmlDetector()
+	}
+
+	if result, err := detector.DetectBest(body); err == nil && result.Confidence >= minSniffConfidence {
+		if enc, name := charset.Lookup(result.Charset); enc != nil {
+			return enc, name
+		}
+	}
+
+	return charmap.Windows1252, "windows-1252"
+}
+
+// isTextual reports whether a media type carries text that should be decoded to UTF-8
+func isTextual(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+xml"),
+		strings.HasSuffix(mediaType, "+json"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/ecmascript":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestDecodeBody(t *testing.T) {
+	shiftJIS, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte("こんにちは世界、日本語のページです。"))
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("你好世界"))
+	latin1Bytes, _ := charmap.Windows1252.NewDecoder().Bytes(shiftJIS)
+	latin1 := string(latin1Bytes)
+
+	tests := []struct {
+		name            string
+		body            []byte
+		contentType     string
+		expected        string
+		expectedCharset string
+	}{
+		{
+			name:            "UTF-8 without declaration",
+			body:            []byte("<p>héllo wörld</p>"),
+			contentType:     "text/html",
+			expected:        "<p>héllo wörld</p>",
+			expectedCharset: "utf-8",
+		},
+		{
+			name:            "UTF-8 byte order mark is stripped",
+			body:            append([]byte{0xEF, 0xBB, 0xBF}, []byte("text")...),
+			contentType:     "text/plain",
+			expected:        "text",
+			expectedCharset: "utf-8",
+		},
+		{
+			name:            "Content-Type charset",
+			body:            []byte{'c', 'a', 'f', 0xE9},
+			contentType:     "text/plain; charset=ISO-8859-1",
+			expected:        "café",
+			expectedCharset: "windows-1252",
+		},
+		{
+			name:            "Content-Type Shift_JIS",
+			body:            shiftJIS,
+			contentType:     "text/html; charset=Shift_JIS",
+			expected:        "こんにちは世界、日本語のページです。",
+			expectedCharset: "shift_jis",
+		},
+		{
+			name:            "meta charset",
+			body:            append([]byte(`<html><head><meta charset="gbk"></head><body>`), gbk...),
+			contentType:     "text/html",
+			expected:        `<html><head><meta charset="gbk"></head><body>你好世界`,
+			expectedCharset: "gbk",
+		},
+		{
+			name: "meta http-equiv",
+			body: append([]byte(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">`+
+				`</head><body>`), shiftJIS...),
+			contentType: "text/html",
+			expected: `<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">` +
+				`</head><body>こんにちは世界、日本語のページです。`,
+			expectedCharset: "shift_jis",
+		},
+		{
+			name:            "Content-Type takes precedence over meta",
+			body:            []byte(`<meta charset="shift_jis">caf` + "\xE9"),
+			contentType:     "text/html; charset=windows-1252",
+			expected:        `<meta charset="shift_jis">café`,
+			expectedCharset: "windows-1252",
+		},
+		{
+			name:            "meta charset is trusted over sniffing",
+			body:            append([]byte(`<html><head><meta charset=iso-8859-1></head><body>`), shiftJIS...),
+			contentType:     "text/html",
+			expected:        `<html><head><meta charset=iso-8859-1></head><body>` + latin1,
+			expectedCharset: "windows-1252",
+		},
+		{
+			name:            "meta utf-8 is trusted over sniffing",
+			body:            append([]byte(`<meta charset="utf-8">`), shiftJIS...),
+			contentType:     "text/html",
+			expected:        `<meta charset="utf-8">` + string(shiftJIS),
+			expectedCharset: "utf-8",
+		},
+		{
+			name:            "undeclared legacy encoding is sniffed",
+			body:            shiftJIS,
+			contentType:     "text/plain",
+			expected:        "こんにちは世界、日本語のページです。",
+			expectedCharset: "shift_jis",
+		},
+		{
+			name:            "binary content is untouched",
+			body:            []byte{0x89, 'P', 'N', 'G'},
+			contentType:     "image/png",
+			expected:        "\x89PNG",
+			expectedCharset: "",
+		},
+		{
+			name:            "binary content without Content-Type is untouched",
+			body:            []byte("%PDF-1.4\n\xe2\xe3\xcf\xd3\n"),
+			contentType:     "",
+			expected:        "%PDF-1.4\n\xe2\xe3\xcf\xd3\n",
+			expectedCharset: "",
+		},
+		{
+			name:            "text without Content-Type is sniffed",
+			body:            shiftJIS,
+			contentType:     "",
+			expected:        "こんにちは世界、日本語のページです。",
+			expectedCharset: "shift_jis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, charset := decodeBody(tt.body, tt.contentType)
+			if string(decoded) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, decoded)
+			}
+			if charset != tt.expectedCharset {
+				t.Errorf("expected charset %q, got %q", tt.expectedCharset, charset)
+			}
+		})
+	}
+}
+
+func TestIsTextual(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		expected  bool
+	}{
+		{"text/html", true},
+		{"text/plain", true},
+		{"application/json", true},
+		{"application/ld+json", true},
+		{"application/atom+xml", true},
+		{"application/pdf", false},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			if result := isTextual(tt.mediaType); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
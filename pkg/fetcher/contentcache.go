@@ -0,0 +1,44 @@
+package fetcher
+
+import (
+	"context"
+	"log"
+
+	"github.com/stackloklabs/gofetch/pkg/cache"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// WithContentCache keeps up to maxBytes of processed documents, keyed by URL,
+// body digest and processing options, so that paging through a document
+// converts it only once
+func WithContentCache(maxBytes int64) Option {
+	return func(f *HTTPFetcher) {
+		f.documents = cache.NewLRU[string](maxBytes, documentSize)
+	}
+}
+
+// processDocument converts a body with the processor, reusing the document of
+// an identical earlier conversion
+func (f *HTTPFetcher) processDocument(ctx context.Context, in *processor.Input) (*processor.Document, error) {
+	if f.documents == nil {
+		return f.processor.Process(ctx, in)
+	}
+
+	key := in.CacheKey()
+	if doc, ok := f.documents.Get(key); ok {
+		log.Printf("Reusing processed content of %s", in.URL)
+		return doc, nil
+	}
+
+	doc, err := f.processor.Process(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	f.documents.Add(key, doc, 0)
+	return doc, nil
+}
+
+// documentSize approximates the memory a processed document occupies, in bytes
+func documentSize(doc *processor.Document) int64 {
+	return int64(len(doc.Content) + len(doc.Title) + len(doc.Byline))
+}
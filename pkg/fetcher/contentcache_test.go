@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stackloklabs/gofetch/pkg/cache"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+func TestContentCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	WithHTTPCache(cache.NewMemoryStore(1024 * 1024))(fetcher)
+	WithContentCache(1024 * 1024)(fetcher)
+
+	var conversions atomic.Int32
+	fetcher.processor.RegisterHandler("text/plain", processor.ContentHandlerFunc(
+		func(_ context.Context, in *processor.Input) (*processor.Document, error) {
+			conversions.Add(1)
+			return &processor.Document{Content: string(in.Body)}, nil
+		}))
+
+	// Paging through the document converts it once
+	maxLength := 4
+	for _, start := range []int{0, 4, 8} {
+		result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, MaxLength: &maxLength, StartIndex: &start})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Page.Start != start || result.Page.Total != 10 {
+			t.Errorf("expected a page at %d of 10, got %d of %d", start, result.Page.Start, result.Page.Total)
+		}
+	}
+	if conversions.Load() != 1 {
+		t.Errorf("expected 1 conversion, got %d", conversions.Load())
+	}
+
+	// Other processing options convert it again
+	if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Raw: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conversions.Load() != 2 {
+		t.Errorf("expected raw content to be converted separately, got %d conversions", conversions.Load())
+	}
+}
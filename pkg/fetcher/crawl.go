@@ -0,0 +1,192 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// CrawlScope limits the links a crawl follows
+type CrawlScope string
+
+// Crawl scopes
+const (
+	// ScopePath follows links on the seed's host under a path prefix, by default the seed's directory
+	ScopePath CrawlScope = "path"
+	// ScopeHost follows links anywhere on the seed's host
+	ScopeHost CrawlScope = "host"
+)
+
+// CrawlRequest holds the parameters for a crawl
+type CrawlRequest struct {
+	// Seed is the URL the crawl starts from
+	Seed  string
+	Scope CrawlScope
+	// PathPrefix overrides the path prefix of ScopePath
+	PathPrefix string
+	// MaxDepth is the number of links followed away from the seed
+	MaxDepth int
+	// MaxPages caps the number of pages fetched, including failed ones
+	MaxPages int
+	// Page holds the processing options applied to every page; its URL is ignored
+	Page FetchRequest
+	// Progress, if set, is called after each page with the number of pages
+	// crawled and the number crawled or queued so far
+	Progress func(page *CrawlPage, done, total int)
+}
+
+// CrawlPage is a page visited by a crawl
+type CrawlPage struct {
+	URL   string
+	Depth int
+	// Result is the fetched page, or nil if fetching it failed
+	Result *FetchResult
+	Err    error
+}
+
+// crawlTarget is a queued URL with its distance from the seed
+type crawlTarget struct {
+	url   string
+	depth int
+}
+
+// crawler holds the state of a breadth-first crawl
+type crawler struct {
+	fetcher *HTTPFetcher
+	req     *CrawlRequest
+	scope   *crawlScope
+	queue   []crawlTarget
+	seen    map[string]bool
+}
+
+// Crawl fetches the seed and, breadth first, the HTML pages it links to within
+// the scope, up to the depth and page limits. Pages disallowed by robots.txt
+// are not queued and every request waits for its host's turn. Pages that fail
+// are reported with their error rather than ending the crawl; only an invalid
+// seed or a cancelled context do.
+func (f *HTTPFetcher) Crawl(ctx context.Context, req *CrawlRequest) ([]*CrawlPage, error) {
+	seed, err := url.Parse(req.Seed)
+	if err != nil || (seed.Scheme != "http" && seed.Scheme != "https") || seed.Host == "" {
+		return nil, fmt.Errorf("invalid seed URL %q", req.Seed)
+	}
+	seed.Fragment = ""
+	scope, err := newCrawlScope(seed, req.Scope, req.PathPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &crawler{
+		fetcher: f,
+		req:     req,
+		scope:   scope,
+		queue:   []crawlTarget{{url: seed.String()}},
+		seen:    map[string]bool{seed.String(): true},
+	}
+
+	var pages []*CrawlPage
+	for len(c.queue) > 0 && len(pages) < req.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("crawl of %s interrupted: %w", req.Seed, err)
+		}
+
+		target := c.queue[0]
+		c.queue = c.queue[1:]
+
+		page := &CrawlPage{URL: target.url, Depth: target.depth}
+		pageReq := req.Page
+		pageReq.URL = target.url
+		page.Result, page.Err = f.crawlPage(ctx, &pageReq)
+		pages = append(pages, page)
+		c.follow(ctx, page)
+
+		if req.Progress != nil {
+			req.Progress(page, len(pages), min(len(pages)+len(c.queue), req.MaxPages))
+		}
+	}
+
+	log.Printf("Crawl of %s completed: %d pages, %d left in the queue", req.Seed, len(pages), len(c.queue))
+	return pages, nil
+}
+
+// follow queues the unseen links of a page that are within the scope and
+// allowed by robots.txt, unless the page is at the maximum depth
+func (c *crawler) follow(ctx context.Context, page *CrawlPage) {
+	if page.Result == nil {
+		return
+	}
+	c.seen[page.Result.FinalURL] = true
+	if page.Depth >= c.req.MaxDepth {
+		return
+	}
+
+	for _, link := range page.Result.Links {
+		if c.seen[link.URL] || link.FileType != "html" || !c.scope.contains(link.URL) {
+			continue
+		}
+		c.seen[link.URL] = true
+		if err := c.fetcher.robotsChecker.Check(ctx, link.URL); err != nil {
+			continue
+		}
+		c.queue = append(c.queue, crawlTarget{url: link.URL, depth: page.Depth + 1})
+	}
+}
+
+// crawlPage fetches and processes a page, collecting the links of HTML pages
+func (f *HTTPFetcher) crawlPage(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	result, body, err := f.download(ctx, req.URL, req.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if isHTML(processor.MediaType(result.ContentType, body)) {
+		result.Links = processor.ExtractLinks(body, result.FinalURL, processor.LinkOptions{})
+	}
+
+	if err := f.process(ctx, result, body, req); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// crawlScope decides which links a crawl follows
+type crawlScope struct {
+	host   string
+	prefix string
+}
+
+// newCrawlScope derives the scope of a crawl from its seed
+func newCrawlScope(seed *url.URL, scope CrawlScope, pathPrefix string) (*crawlScope, error) {
+	s := &crawlScope{host: strings.ToLower(seed.Hostname())}
+	switch scope {
+	case ScopeHost:
+		s.prefix = "/"
+	case ScopePath, "":
+		s.prefix = pathPrefix
+		if s.prefix == "" {
+			s.prefix = seed.EscapedPath()[:strings.LastIndex(seed.EscapedPath(), "/")+1]
+		}
+		if !strings.HasPrefix(s.prefix, "/") {
+			s.prefix = "/" + s.prefix
+		}
+	default:
+		return nil, fmt.Errorf("invalid crawl scope %q: expected %s or %s", scope, ScopePath, ScopeHost)
+	}
+	return s, nil
+}
+
+// contains reports whether a link is within the scope
+func (s *crawlScope) contains(link string) bool {
+	target, err := url.Parse(link)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return false
+	}
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(target.Hostname()) == s.host && strings.HasPrefix(path, s.prefix)
+}
@@ -0,0 +1,129 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// createCrawlServer serves a small docs site with a guide section
+func createCrawlServer() *httptest.Server {
+	pages := map[string]string{
+		"/guide/":              `<a href="intro">Intro</a> <a href="setup#proxy">Setup</a> <a href="/blog/">Blog</a> <a href="manual.pdf">PDF</a>`,
+		"/guide/intro":         `<h1>Intro</h1><a href="/guide/">Back</a> <a href="advanced/">Advanced</a> <a href="/guide/private/x">Secret</a>`,
+		"/guide/setup":         `<h1>Setup</h1><p>Set the proxy.</p>`,
+		"/guide/advanced/":     `<a href="deep">Deep</a>`,
+		"/guide/advanced/deep": `<p>Deep page</p>`,
+		"/blog/":               `<p>Blog</p>`,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /guide/private/\n"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + body + "</body></html>"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCrawl(t *testing.T) {
+	server := createCrawlServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	var progress []int
+	pages, err := fetcher.Crawl(context.Background(), &CrawlRequest{
+		Seed:     server.URL + "/guide/",
+		MaxDepth: 2,
+		MaxPages: 10,
+		Progress: func(_ *CrawlPage, done, _ int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var visited []string
+	for _, page := range pages {
+		if page.Err != nil {
+			t.Errorf("unexpected error for %s: %v", page.URL, page.Err)
+		}
+		visited = append(visited, strings.TrimPrefix(page.URL, server.URL))
+	}
+	expected := "/guide/ /guide/intro /guide/setup /guide/advanced/"
+	if strings.Join(visited, " ") != expected {
+		t.Errorf("expected to visit %s in breadth-first order, got %v", expected, visited)
+	}
+	if pages[2].Depth != 1 || pages[3].Depth != 2 || !strings.Contains(pages[2].Result.Content, "Set the proxy.") {
+		t.Errorf("unexpected setup pages %+v, %+v", pages[2], pages[3])
+	}
+	if len(progress) != 4 || progress[3] != 4 {
+		t.Errorf("expected progress after every page, got %v", progress)
+	}
+}
+
+func TestCrawlLimits(t *testing.T) {
+	server := createCrawlServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	pages, err := fetcher.Crawl(context.Background(), &CrawlRequest{Seed: server.URL + "/guide/", MaxDepth: 5, MaxPages: 2})
+	if err != nil || len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d (%v)", len(pages), err)
+	}
+
+	pages, err = fetcher.Crawl(context.Background(), &CrawlRequest{Seed: server.URL + "/guide/", Scope: ScopeHost, MaxDepth: 1, MaxPages: 10})
+	if err != nil || len(pages) != 4 || pages[3].URL != server.URL+"/blog/" {
+		t.Errorf("expected the host scope to reach the blog, got %d pages (%v)", len(pages), err)
+	}
+
+	if _, err := fetcher.Crawl(context.Background(), &CrawlRequest{Seed: "ftp://example.com/", MaxPages: 1}); err == nil {
+		t.Error("expected an invalid seed to be rejected")
+	}
+	if _, err := fetcher.Crawl(context.Background(), &CrawlRequest{Seed: server.URL, Scope: "site", MaxPages: 1}); err == nil {
+		t.Error("expected an invalid scope to be rejected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.Crawl(ctx, &CrawlRequest{Seed: server.URL + "/guide/", MaxPages: 1}); err == nil {
+		t.Error("expected a cancelled crawl to fail")
+	}
+}
+
+func TestCrawlScope(t *testing.T) {
+	seed, _ := url.Parse("https://example.com/docs/guide/start")
+
+	scope, _ := newCrawlScope(seed, ScopePath, "")
+	for link, want := range map[string]bool{
+		"https://example.com/docs/guide/next":   true,
+		"https://EXAMPLE.com/docs/guide/":       true,
+		"https://example.com/docs/other":        false,
+		"https://other.example.com/docs/guide/": false,
+	} {
+		if got := scope.contains(link); got != want {
+			t.Errorf("path scope contains(%s) = %v, want %v", link, got, want)
+		}
+	}
+
+	scope, _ = newCrawlScope(seed, ScopePath, "docs")
+	if !scope.contains("https://example.com/docs/other") {
+		t.Error("expected an explicit path prefix to widen the scope")
+	}
+
+	scope, _ = newCrawlScope(seed, ScopeHost, "")
+	if !scope.contains("https://example.com") || scope.contains("mailto:a@example.com") {
+		t.Error("expected the host scope to cover the whole host")
+	}
+}
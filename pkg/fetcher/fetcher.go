@@ -2,22 +2,100 @@
 package fetcher
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/stackloklabs/gofetch/pkg/cache"
 	"github.com/stackloklabs/gofetch/pkg/processor"
 	"github.com/stackloklabs/gofetch/pkg/robots"
 )
 
+// DefaultMaxBodySize is the default limit on the size of a response body, after decompression
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
+// OversizePolicy determines what happens when a response body exceeds the size limit
+type OversizePolicy string
+
+// Oversize policies
+const (
+	// OversizeError fails the fetch with a BodyTooLargeError
+	OversizeError OversizePolicy = "error"
+	// OversizeTruncate returns the content read up to the limit, followed by a truncation marker
+	OversizeTruncate OversizePolicy = "truncate"
+)
+
+// BodyTooLargeError is returned when a response body exceeds the configured size limit
+type BodyTooLargeError struct {
+	URL   string
+	Limit int64
+	// ContentLength is the size announced by the server, or -1 if the body was
+	// found to be too large while streaming it
+	ContentLength int64
+}
+
+// Error implements the error interface
+func (e *BodyTooLargeError) Error() string {
+	if e.ContentLength >= 0 {
+		return fmt.Sprintf("response from %s is too large: %d bytes exceeds the %d byte limit", e.URL, e.ContentLength, e.Limit)
+	}
+	return fmt.Sprintf("response from %s is too large: exceeds the %d byte limit", e.URL, e.Limit)
+}
+
+// StatusError is returned when a server answers with a status other than 200 OK
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// Status is the status line text, such as "404 Not Found"
+	Status string
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Status)
+}
+
+// maxCrawlDelay caps the Crawl-delay honored from robots.txt so a single host cannot stall callers indefinitely
+const maxCrawlDelay = time.Minute
+
 // HTTPFetcher handles HTTP requests and content retrieval
 type HTTPFetcher struct {
-	httpClient    *http.Client
-	robotsChecker *robots.Checker
-	processor     *processor.ContentProcessor
-	userAgent     string
+	httpClient      *http.Client
+	robotsChecker   *robots.Checker
+	processor       *processor.ContentProcessor
+	userAgent       string
+	minHostInterval time.Duration
+	maxBodySize     int64
+	oversizePolicy  OversizePolicy
+	scheduler       *hostScheduler
+	httpCache       cache.Store
+	documents       *cache.LRU[string, *processor.Document]
+}
+
+// Option configures an HTTPFetcher
+type Option func(*HTTPFetcher)
+
+// WithMinHostInterval sets the minimum time between requests to the same host,
+// used when robots.txt declares no longer Crawl-delay
+func WithMinHostInterval(interval time.Duration) Option {
+	return func(f *HTTPFetcher) {
+		f.minHostInterval = interval
+	}
+}
+
+// WithMaxBodySize limits the size of response bodies, measured after any
+// transparent decompression, and sets what happens to larger responses.
+// A limit of zero or less disables the check.
+func WithMaxBodySize(limit int64, policy OversizePolicy) Option {
+	return func(f *HTTPFetcher) {
+		f.maxBodySize = limit
+		f.oversizePolicy = policy
+	}
 }
 
 // NewHTTPFetcher creates a new HTTP fetcher instance
@@ -26,13 +104,23 @@ func NewHTTPFetcher(
 	robotsChecker *robots.Checker,
 	contentProcessor *processor.ContentProcessor,
 	userAgent string,
+	opts ...Option,
 ) *HTTPFetcher {
-	return &HTTPFetcher{
-		httpClient:    httpClient,
-		robotsChecker: robotsChecker,
-		processor:     contentProcessor,
-		userAgent:     userAgent,
+	f := &HTTPFetcher{
+		httpClient:     httpClient,
+		robotsChecker:  robotsChecker,
+		processor:      contentProcessor,
+		userAgent:      userAgent,
+		maxBodySize:    DefaultMaxBodySize,
+		oversizePolicy: OversizeError,
+		scheduler:      newHostScheduler(),
 	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
 }
 
 // FetchRequest holds the parameters for a fetch request
@@ -40,76 +128,401 @@ type FetchRequest struct {
 	URL        string
 	MaxLength  *int
 	StartIndex *int
-	Raw        bool
+	// Unit measures MaxLength and StartIndex; empty means characters
+	Unit processor.Unit
+	Raw  bool
+	// BoundaryAware truncates on structural markdown boundaries
+	BoundaryAware bool
+	// Pages selects the pages of paged documents such as PDF; nil selects every page
+	Pages []processor.PageRange
+	// JSONPath selects a sub-tree of JSON responses
+	JSONPath *processor.JSONPath
+	// JSONShape summarizes JSON responses instead of returning their values
+	JSONShape bool
+	// Since drops feed entries published before it
+	Since *time.Time
+	// Limit caps the number of feed entries returned; zero returns them all
+	Limit int
+	// DiscoverFeed fetches the first feed an HTML page advertises instead of the page itself
+	DiscoverFeed bool
+	// Metadata prefixes the content of HTML pages with a header listing their metadata
+	Metadata bool
+	// AbsoluteURLs rewrites relative link and image URLs in HTML pages against the final URL
+	AbsoluteURLs bool
+	// Selection converts only the matching elements of HTML pages, bypassing readability
+	Selection *processor.Selection
+	// Section keeps only the content under a heading, named by a path such as
+	// "Configuration > Proxy" or by its anchor
+	Section string
+	// Outline returns the heading outline of the content instead of the content
+	Outline bool
+	// Cache chooses how the HTTP cache is used; empty means CacheDefault
+	Cache CacheMode
+}
+
+// FetchResult holds fetched content along with details about how it was retrieved
+type FetchResult struct {
+	Content string
+	// FinalURL is the URL the content was served from, after following redirects
+	FinalURL    string
+	Status      int
+	ContentType string
+	// Title, Byline and Published are the article metadata extracted from HTML pages
+	Title     string
+	Byline    string
+	Published *time.Time
+	// PageCount is the number of pages of paged documents such as PDF
+	PageCount int
+	// FetchedAt is when the response was received
+	FetchedAt time.Time
+	// Page describes the window of content returned
+	Page *processor.Page
+	// Truncated reports whether the response body was cut off at the size limit
+	Truncated bool
+	// Charset is the character encoding the body was decoded from, empty for non-textual content
+	Charset string
+	// Delay is how long the request waited for its turn under per-host politeness rules
+	Delay time.Duration
+	// FeedURL is the feed discovered on the requested page, when feed discovery was asked for
+	FeedURL string
+	// Metadata is the OpenGraph, Twitter card, JSON-LD and microdata metadata of HTML pages
+	Metadata *processor.Metadata
+	// Links are the links found on the page by FetchLinks
+	Links []processor.Link
+	// Outline lists the headings of the content, when an outline was asked for
+	Outline []processor.Heading
+	// CacheStatus tells whether the response came from the network, the HTTP
+	// cache or a revalidated cache entry
+	CacheStatus string
 }
 
 // FetchURL retrieves and processes content from the specified URL
 func (f *HTTPFetcher) FetchURL(req *FetchRequest) (string, error) {
-	log.Printf("Fetching URL: %s", req.URL)
+	return f.FetchURLContext(context.Background(), req)
+}
+
+// FetchURLContext is like FetchURL but aborts network I/O and processing when the context is done
+func (f *HTTPFetcher) FetchURLContext(ctx context.Context, req *FetchRequest) (string, error) {
+	result, err := f.Fetch(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// Fetch retrieves and processes content from the specified URL, waiting for the
+// host's politeness interval before issuing the request. The context bounds every
+// step, from the robots.txt lookup to HTML processing.
+func (f *HTTPFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	result, err := f.FetchDocument(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply formatting
+	page := f.Paginate(result.Content, req)
+	result.Content = page.Content
+	result.Page = page
+
+	log.Printf("Fetch completed successfully for %s, returning %s %d-%d of %d", req.URL, page.Unit, page.Start, page.End, page.Total)
+	return result, nil
+}
+
+// Paginate returns the window of content selected by a request's StartIndex,
+// MaxLength, Unit and BoundaryAware options
+func (f *HTTPFetcher) Paginate(content string, req *FetchRequest) *processor.Page {
+	return f.processor.Paginate(content, processor.PageOptions{
+		StartIndex:    req.StartIndex,
+		MaxLength:     req.MaxLength,
+		Unit:          req.Unit,
+		BoundaryAware: req.BoundaryAware,
+	})
+}
+
+// FetchDocument is like Fetch but returns the whole processed content, leaving
+// MaxLength, StartIndex and BoundaryAware unused
+func (f *HTTPFetcher) FetchDocument(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	result, err := f.retrieve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Follow a discovered feed link, as its own politely scheduled request
+	if result.FeedURL != "" {
+		log.Printf("Discovered feed %s on %s", result.FeedURL, req.URL)
+		feedReq := *req
+		feedReq.URL = result.FeedURL
+		feedReq.DiscoverFeed = false
+
+		feed, err := f.retrieve(ctx, &feedReq)
+		if err != nil {
+			return nil, fmt.Errorf("fetching discovered feed %s: %w", result.FeedURL, err)
+		}
+		feed.Delay += result.Delay
+		feed.FeedURL = result.FeedURL
+		result = feed
+	}
+
+	if req.Section != "" {
+		section, err := processor.Section(result.Content, req.Section)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = section
+	}
+
+	if req.Outline {
+		result.Outline = processor.Outline(result.Content, req.Unit)
+		result.Content = processor.FormatOutline(result.Outline, req.Unit)
+	}
+
+	return result, nil
+}
+
+// FetchLinks retrieves an HTML page and extracts the links on it, subject to
+// the same robots.txt and politeness rules as Fetch
+func (f *HTTPFetcher) FetchLinks(ctx context.Context, targetURL string, opts processor.LinkOptions) (*FetchResult, error) {
+	result, body, err := f.download(ctx, targetURL, CacheDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType := processor.MediaType(result.ContentType, body)
+	if !isHTML(mediaType) {
+		return nil, fmt.Errorf("%s is not an HTML page: content type %s", result.FinalURL, mediaType)
+	}
+
+	result.Links = processor.ExtractLinks(body, result.FinalURL, opts)
+
+	log.Printf("Extracted %d links from %s", len(result.Links), result.FinalURL)
+	return result, nil
+}
+
+// isHTML reports whether a media type is an HTML page
+func isHTML(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// retrieve downloads the URL and converts the body with the handler for its media type
+func (f *HTTPFetcher) retrieve(ctx context.Context, req *FetchRequest) (*FetchResult, error) {
+	result, body, err := f.download(ctx, req.URL, req.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.process(ctx, result, body, req); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// download checks robots.txt, waits for the host's turn and fetches the URL,
+// returning the body transcoded to UTF-8. A stored response the cache mode
+// allows is served without either; a stale one is revalidated.
+func (f *HTTPFetcher) download(ctx context.Context, targetURL string, mode CacheMode) (*FetchResult, []byte, error) {
+	log.Printf("Fetching URL: %s", targetURL)
+
+	stored, usable, err := f.cachedResponse(targetURL, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if usable {
+		log.Printf("Serving %s from the HTTP cache", targetURL)
+		result, body := newResult(stored, CacheStatusHit)
+		return result, body, nil
+	}
 
 	// Check robots.txt
-	if !f.robotsChecker.IsAllowed(req.URL) {
-		log.Printf("Access denied by robots.txt for URL: %s", req.URL)
-		return "", fmt.Errorf("access to %s is disallowed by robots.txt", req.URL)
+	if err := f.robotsChecker.Check(ctx, targetURL); err != nil {
+		log.Printf("Access denied by robots.txt for URL: %s", targetURL)
+		return nil, nil, err
+	}
+
+	// Wait for this host's turn
+	delay, err := f.waitForHost(ctx, targetURL)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Fetch the content
-	content, err := f.fetchURL(req.URL, req.Raw)
+	resp, cacheStatus, err := f.fetchURL(ctx, targetURL, stored)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
+	result, body := newResult(resp, cacheStatus)
+	result.Delay = delay
 
-	// Apply formatting
-	formattedContent := f.processor.FormatContent(content, req.StartIndex, req.MaxLength)
+	return result, body, nil
+}
+
+// waitForHost blocks until the URL's host may be contacted again, honoring the
+// robots.txt Crawl-delay or the configured minimum interval, whichever is longer
+func (f *HTTPFetcher) waitForHost(ctx context.Context, targetURL string) (time.Duration, error) {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL: %v", err)
+	}
 
-	log.Printf("Fetch completed successfully for %s, returning %d characters", req.URL, len(formattedContent))
-	return formattedContent, nil
+	interval := max(min(f.robotsChecker.CrawlDelay(ctx, targetURL), maxCrawlDelay), f.minHostInterval)
+
+	delay, err := f.scheduler.wait(ctx, strings.ToLower(parsedURL.Host), interval)
+	if err != nil {
+		return 0, fmt.Errorf("cancelled while waiting for %s: %w", parsedURL.Host, err)
+	}
+	if delay > 0 {
+		log.Printf("Waited %s before fetching %s (interval %s)", delay, targetURL, interval)
+	}
+
+	return delay, nil
 }
 
-// fetchURL retrieves content from the specified URL
-func (f *HTTPFetcher) fetchURL(url string, raw bool) (string, error) {
+// fetchURL retrieves content from the requested URL, revalidating the stored
+// response if there is one, and keeps cacheable responses in the HTTP cache
+func (f *HTTPFetcher) fetchURL(ctx context.Context, targetURL string, stored *cache.Response) (*cache.Response, string, error) {
 	// Create HTTP request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
 	if err != nil {
-		log.Printf("Failed to create HTTP request for %s: %v", url, err)
-		return "", fmt.Errorf("failed to create request: %v", err)
+		log.Printf("Failed to create HTTP request for %s: %v", targetURL, err)
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set headers
 	req.Header.Set("User-Agent", f.userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if stored != nil {
+		stored.SetConditional(req.Header)
+	}
 
 	// Make HTTP request
+	requestTime := time.Now()
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		log.Printf("HTTP request failed for %s: %v", url, err)
-		return "", fmt.Errorf("failed to fetch URL: %v", err)
+		log.Printf("HTTP request failed for %s: %v", targetURL, err)
+		return nil, "", fmt.Errorf("failed to fetch URL: %w", err)
 	}
 	defer resp.Body.Close()
+	responseTime := time.Now()
 
-	log.Printf("HTTP %d response from %s (Content-Type: %s)", resp.StatusCode, url, resp.Header.Get("Content-Type"))
+	log.Printf("HTTP %d response from %s (Content-Type: %s)", resp.StatusCode, targetURL, resp.Header.Get("Content-Type"))
+
+	// The stored response is still valid
+	if resp.StatusCode == http.StatusNotModified && stored != nil {
+		revalidated := stored.Revalidated(resp.Header, requestTime, responseTime)
+		f.storeResponse(targetURL, revalidated)
+		return revalidated, CacheStatusRevalidated, nil
+	}
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Non-200 status code %d for %s: %s", resp.StatusCode, url, resp.Status)
-		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		log.Printf("Non-200 status code %d for %s: %s", resp.StatusCode, targetURL, resp.Status)
+		return nil, "", &StatusError{URL: targetURL, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	body, truncated, err := f.readBody(resp, targetURL)
+	if err != nil {
+		log.Printf("Failed to read response body from %s: %v", targetURL, err)
+		return nil, "", err
+	}
+
+	log.Printf("Successfully fetched %d bytes from %s", len(body), targetURL)
+
+	fetched := &cache.Response{
+		URL:          resp.Request.URL.String(),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body:         body,
+		Truncated:    truncated,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+	if cache.Storable(resp) {
+		f.storeResponse(targetURL, fetched)
+	}
+
+	return fetched, CacheStatusMiss, nil
+}
+
+// process converts a downloaded body with the handler for its media type
+func (f *HTTPFetcher) process(ctx context.Context, result *FetchResult, body []byte, fetchReq *FetchRequest) error {
+	mediaType := processor.MediaType(result.ContentType, body)
+
+	// Hand back the page's feed instead of the page, if it advertises one
+	if fetchReq.DiscoverFeed && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+		if feeds := processor.DiscoverFeeds(body, result.FinalURL); len(feeds) > 0 {
+			result.FeedURL = feeds[0]
+			return nil
+		}
+		log.Printf("No feed advertised by %s, returning the page", fetchReq.URL)
+	}
+
+	// Convert the body with the handler for its media type
+	doc, err := f.processDocument(ctx, &processor.Input{
+		Body:           body,
+		MediaType:      mediaType,
+		URL:            result.FinalURL,
+		Raw:            fetchReq.Raw,
+		Pages:          fetchReq.Pages,
+		JSONPath:       fetchReq.JSONPath,
+		JSONShape:      fetchReq.JSONShape,
+		Since:          fetchReq.Since,
+		Limit:          fetchReq.Limit,
+		MetadataHeader: fetchReq.Metadata,
+		AbsoluteURLs:   fetchReq.AbsoluteURLs,
+		Selection:      fetchReq.Selection,
+	})
 	if err != nil {
-		log.Printf("Failed to read response body from %s: %v", url, err)
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("processing aborted: %w", err)
+		}
+		log.Printf("Failed to process content from %s: %v", fetchReq.URL, err)
+		return err
+	}
+	result.Content = doc.Content
+	result.Title = doc.Title
+	result.Byline = doc.Byline
+	result.Published = doc.Published
+	result.PageCount = doc.PageCount
+	result.Metadata = doc.Metadata
+
+	if result.Truncated {
+		result.Content += fmt.Sprintf("\n\n[Response truncated at the %d byte size limit.]", f.maxBodySize)
+	}
+
+	return nil
+}
+
+// readBody reads the response body while enforcing the size limit. An announced
+// Content-Length over the limit is rejected before reading; otherwise the body is
+// streamed and at most one byte past the limit is read. Because the transport
+// decompresses transparently, the limit applies to the decompressed size, which
+// also defuses decompression bombs.
+func (f *HTTPFetcher) readBody(resp *http.Response, targetURL string) ([]byte, bool, error) {
+	if f.maxBodySize <= 0 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return body, false, nil
 	}
 
-	log.Printf("Successfully fetched %d bytes from %s", len(body), url)
+	if resp.ContentLength > f.maxBodySize && f.oversizePolicy != OversizeTruncate {
+		return nil, false, &BodyTooLargeError{URL: targetURL, Limit: f.maxBodySize, ContentLength: resp.ContentLength}
+	}
 
-	content := string(body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBodySize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if int64(len(body)) <= f.maxBodySize {
+		return body, false, nil
+	}
 
-	// Process HTML if not raw mode
-	if !raw && strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		content = f.processor.ProcessHTML(content)
+	if f.oversizePolicy != OversizeTruncate {
+		return nil, false, &BodyTooLargeError{URL: targetURL, Limit: f.maxBodySize, ContentLength: -1}
 	}
 
-	return content, nil
+	log.Printf("Truncating response from %s at %d bytes", targetURL, f.maxBodySize)
+	return body[:f.maxBodySize], true, nil
 }
@@ -1,8 +1,16 @@
 package fetcher
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -160,6 +168,407 @@ func TestFetchURL(t *testing.T) {
 	}
 }
 
+func TestFetchResultMetadata(t *testing.T) {
+	server := createMockServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/html", MaxLength: intPtr(5)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.FinalURL != server.URL+"/html" {
+		t.Errorf("expected final URL %q, got %q", server.URL+"/html", result.FinalURL)
+	}
+	if result.Status != http.StatusOK || result.ContentType != "text/html" {
+		t.Errorf("expected 200 text/html, got %d %q", result.Status, result.ContentType)
+	}
+	if result.FetchedAt.IsZero() {
+		t.Error("expected fetch time to be set")
+	}
+	if result.Page == nil || result.Page.Start != 0 || result.Page.End != 5 || !result.Page.Truncated {
+		t.Errorf("expected first 5 characters to be returned, got %+v", result.Page)
+	}
+}
+
+func TestFetchDiscoverFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/feed"></head>` +
+			`<body><p>The blog page.</p></body></html>`))
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Blog</title>` +
+			`<item><title>Hello</title><link>https://example.com/hello</link></item></channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/blog", DiscoverFeed: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FeedURL != server.URL+"/feed" || result.FinalURL != server.URL+"/feed" {
+		t.Errorf("expected the feed at %s/feed, got feed %q from %q", server.URL, result.FeedURL, result.FinalURL)
+	}
+	if !strings.Contains(result.Content, "## [Hello](https://example.com/hello)") {
+		t.Errorf("expected a feed digest, got %q", result.Content)
+	}
+
+	// Without discovery, or without a feed link, the page itself is returned
+	result, err = fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/blog"})
+	if err != nil || result.FeedURL != "" || !strings.Contains(result.Content, "The blog page.") {
+		t.Errorf("expected the page itself, got %+v, %v", result, err)
+	}
+}
+
+func TestFetchLinks(t *testing.T) {
+	server := createMockServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	result, err := fetcher.FetchLinks(context.Background(), server.URL+"/html", processor.LinkOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinalURL != server.URL+"/html" || result.Links == nil || len(result.Links) != 0 {
+		t.Errorf("expected no links on the test page, got %+v", result)
+	}
+
+	if _, err := fetcher.FetchLinks(context.Background(), server.URL+"/json", processor.LinkOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "not an HTML page") {
+		t.Errorf("expected a JSON response to be rejected, got %v", err)
+	}
+}
+
+func TestFetchSectionAndOutline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/markdown")
+		w.Write([]byte("# Docs\n\nIntro.\n\n## Configuration\n\n### Proxy\n\nSet proxy_url.\n\n## Usage\n\nRun it.\n"))
+	}))
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Section: "Configuration > Proxy"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Content != "### Proxy\n\nSet proxy_url." {
+		t.Errorf("expected only the proxy section, got %q", result.Content)
+	}
+
+	result, err = fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Outline: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Outline) != 4 || result.Outline[3].Anchor != "usage" || !strings.Contains(result.Content, "- Usage (#usage)") {
+		t.Errorf("expected an outline of 4 headings, got %+v: %q", result.Outline, result.Content)
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Section: "#missing"}); err == nil {
+		t.Error("expected an error for a missing section")
+	}
+}
+
+func TestFetchDocument(t *testing.T) {
+	body := strings.Repeat("0123456789", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	maxLength := 100
+
+	result, err := fetcher.FetchDocument(context.Background(), &FetchRequest{URL: server.URL, MaxLength: &maxLength})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Content != body || result.Page != nil {
+		t.Errorf("expected the whole content without a page, got %d characters and %+v", len(result.Content), result.Page)
+	}
+}
+
+func TestFetchStatusError(t *testing.T) {
+	server := createMockServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	_, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/error"})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+	if err.Error() != "HTTP 500: 500 Internal Server Error" || statusErr.URL != server.URL+"/error" {
+		t.Errorf("unexpected status error %+v: %v", statusErr, err)
+	}
+}
+
+func TestFetchUnsupportedBinaryContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	fetcher := NewHTTPFetcher(client, robots.NewChecker("TestBot/1.0", true, client),
+		processor.NewContentProcessor(), "TestBot/1.0")
+
+	_, err := fetcher.FetchURL(&FetchRequest{URL: server.URL})
+
+	var unsupported *processor.UnsupportedContentError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedContentError, got %v", err)
+	}
+	if unsupported.MediaType != "image/png" {
+		t.Errorf("expected media type image/png, got %q", unsupported.MediaType)
+	}
+}
+
+// buildPDF writes a minimal one-page PDF document showing text, with the
+// binary comment line real documents start with
+func buildPDF(text string) []byte {
+	content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func TestFetchPDFWithoutContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		// Keep the server from sniffing a Content-Type itself
+		w.Header()["Content-Type"] = nil
+		w.Write(buildPDF("Quarterly report"))
+	}))
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/report"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Charset != "" || !strings.Contains(result.Content, "Quarterly report") {
+		t.Errorf("expected the untouched PDF to be extracted, got %q (charset %q)", result.Content, result.Charset)
+	}
+}
+
+func TestFetchHonorsCrawlDelay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 0.1"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+
+	first, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/page"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Delay != 0 {
+		t.Errorf("expected first request not to wait, waited %s", first.Delay)
+	}
+
+	second, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/page"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Delay <= 0 {
+		t.Error("expected second request to wait for the crawl delay")
+	}
+}
+
+func TestFetchDownloadsUncacheableRobotsOnce(t *testing.T) {
+	var robotsRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		robotsRequests.Add(1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("User-agent: *\nDisallow: /private"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/page"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The rules check and the crawl delay lookup share one download
+	if robotsRequests.Load() != 1 {
+		t.Errorf("expected robots.txt to be downloaded once, got %d requests", robotsRequests.Load())
+	}
+}
+
+func TestFetchMinHostInterval(t *testing.T) {
+	server := createMockServer()
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	fetcher := NewHTTPFetcher(client, robots.NewChecker("TestBot/1.0", true, client),
+		processor.NewContentProcessor(), "TestBot/1.0", WithMinHostInterval(time.Hour))
+
+	if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL + "/json"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := fetcher.Fetch(ctx, &FetchRequest{URL: server.URL + "/json"}); err == nil {
+		t.Error("expected the wait for the next slot to be cancelled")
+	}
+}
+
+func TestFetchURLContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	fetcher := NewHTTPFetcher(client, robots.NewChecker("TestBot/1.0", true, client),
+		processor.NewContentProcessor(), "TestBot/1.0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := fetcher.FetchURLContext(ctx, &FetchRequest{URL: server.URL})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected request to be aborted promptly, took %s", elapsed)
+	}
+}
+
+func TestFetchBodySizeLimit(t *testing.T) {
+	body := strings.Repeat("a", 1000)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/announced", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("/streamed", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.(http.Flusher).Flush() // force chunked encoding so no Content-Length is sent
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("/bomb", func(w http.ResponseWriter, _ *http.Request) {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(bytes.Repeat([]byte{0}, 1<<20))
+		gz.Close()
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	newFetcher := func(policy OversizePolicy) *HTTPFetcher {
+		return NewHTTPFetcher(client, robots.NewChecker("TestBot/1.0", true, client),
+			processor.NewContentProcessor(), "TestBot/1.0", WithMaxBodySize(100, policy))
+	}
+
+	tests := []struct {
+		name                  string
+		path                  string
+		expectedContentLength int64
+	}{
+		{"announced length is rejected up front", "/announced", 1000},
+		{"streamed body is rejected at the limit", "/streamed", -1},
+		{"decompressed size is limited", "/bomb", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFetcher(OversizeError).FetchURL(&FetchRequest{URL: server.URL + tt.path})
+
+			var tooLarge *BodyTooLargeError
+			if !errors.As(err, &tooLarge) {
+				t.Fatalf("expected BodyTooLargeError, got %v", err)
+			}
+			if tooLarge.Limit != 100 {
+				t.Errorf("expected limit 100, got %d", tooLarge.Limit)
+			}
+			if tooLarge.ContentLength != tt.expectedContentLength {
+				t.Errorf("expected content length %d, got %d", tt.expectedContentLength, tooLarge.ContentLength)
+			}
+		})
+
+		t.Run(tt.name+" with truncate policy", func(t *testing.T) {
+			result, err := newFetcher(OversizeTruncate).Fetch(context.Background(), &FetchRequest{URL: server.URL + tt.path})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.Truncated {
+				t.Error("expected result to be marked as truncated")
+			}
+			if !strings.Contains(result.Content, "[Response truncated at the 100 byte size limit.]") {
+				t.Errorf("expected truncation marker, got %q", result.Content)
+			}
+		})
+	}
+
+	result, err := newFetcher(OversizeError).Fetch(context.Background(), &FetchRequest{URL: server.URL + "/announced"})
+	if err == nil || result != nil {
+		t.Error("expected oversized response to fail without a result")
+	}
+}
+
 // intPtr returns a pointer to an int
 func intPtr(i int) *int {
 	return &i
@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/stackloklabs/gofetch/pkg/cache"
+)
+
+// CacheMode chooses how a request uses the HTTP cache, after the cache modes of the Fetch standard
+type CacheMode string
+
+// Cache modes
+const (
+	// CacheDefault serves fresh stored responses and revalidates stale ones
+	CacheDefault CacheMode = "default"
+	// CacheNoCache revalidates any stored response before using it
+	CacheNoCache CacheMode = "no-cache"
+	// CacheOnlyIfCached serves any stored response, however stale, and never
+	// contacts the server
+	CacheOnlyIfCached CacheMode = "only-if-cached"
+	// CacheForceCache serves any stored response, however stale, and fetches
+	// only what is not stored
+	CacheForceCache CacheMode = "force-cache"
+)
+
+// How a response was obtained, reported in FetchResult.CacheStatus
+const (
+	CacheStatusMiss        = "miss"
+	CacheStatusHit         = "hit"
+	CacheStatusRevalidated = "revalidated"
+)
+
+// ErrNotCached is returned for CacheOnlyIfCached requests without a stored response
+var ErrNotCached = errors.New("no cached response")
+
+// ParseCacheMode parses a cache mode name; the empty string selects CacheDefault
+func ParseCacheMode(value string) (CacheMode, error) {
+	switch mode := CacheMode(value); mode {
+	case "":
+		return CacheDefault, nil
+	case CacheDefault, CacheNoCache, CacheOnlyIfCached, CacheForceCache:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid cache mode %q: expected %s, %s, %s or %s",
+			value, CacheDefault, CacheNoCache, CacheOnlyIfCached, CacheForceCache)
+	}
+}
+
+// WithHTTPCache stores responses in store and serves requests from it
+// according to their cache mode. Without it, every request goes to the network.
+func WithHTTPCache(store cache.Store) Option {
+	return func(f *HTTPFetcher) {
+		f.httpCache = store
+	}
+}
+
+// cachedResponse looks up the stored response for a URL and reports whether it
+// may be served without contacting the server under the cache mode
+func (f *HTTPFetcher) cachedResponse(targetURL string, mode CacheMode) (*cache.Response, bool, error) {
+	var stored *cache.Response
+	if f.httpCache != nil {
+		stored, _ = f.httpCache.Get(targetURL)
+	}
+
+	switch {
+	case stored == nil && mode == CacheOnlyIfCached:
+		return nil, false, fmt.Errorf("%s: %w", targetURL, ErrNotCached)
+	case stored == nil:
+		return nil, false, nil
+	case mode == CacheOnlyIfCached || mode == CacheForceCache:
+		return stored, true, nil
+	case mode == CacheNoCache:
+		return stored, false, nil
+	default:
+		return stored, stored.Fresh(time.Now()), nil
+	}
+}
+
+// storeResponse keeps a response in the HTTP cache, if there is one
+func (f *HTTPFetcher) storeResponse(targetURL string, resp *cache.Response) {
+	if f.httpCache != nil {
+		f.httpCache.Set(targetURL, resp)
+		log.Printf("Stored response for %s in the HTTP cache", targetURL)
+	}
+}
+
+// newResult describes a response and returns its body transcoded to UTF-8
+func newResult(resp *cache.Response, cacheStatus string) (*FetchResult, []byte) {
+	result := &FetchResult{
+		FinalURL:    resp.URL,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		FetchedAt:   resp.ResponseTime,
+		Truncated:   resp.Truncated,
+		CacheStatus: cacheStatus,
+	}
+
+	// Transcode text to UTF-8 before any processing
+	var body []byte
+	body, result.Charset = decodeBody(resp.Body, result.ContentType)
+
+	return result, body
+}
@@ -0,0 +1,132 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stackloklabs/gofetch/pkg/cache"
+)
+
+// createCachingServer serves pages with the given Cache-Control and an ETag,
+// answering conditional requests with 304 Not Modified. It counts the page
+// requests and the 304 answers, ignoring robots.txt.
+func createCachingServer(cacheControl string, requests, notModified *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("cached content"))
+	}))
+}
+
+// createCachingFetcher returns a test fetcher with an in-memory HTTP cache
+func createCachingFetcher() *HTTPFetcher {
+	f := createTestFetcher()
+	WithHTTPCache(cache.NewMemoryStore(1024 * 1024))(f)
+	return f
+}
+
+func TestHTTPCacheServesFreshResponses(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := createCachingServer("max-age=300", &requests, &notModified)
+	defer server.Close()
+
+	fetcher := createCachingFetcher()
+	statuses := []string{CacheStatusMiss, CacheStatusHit, CacheStatusHit}
+	for _, expected := range statuses {
+		result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.CacheStatus != expected || result.Content != "cached content" {
+			t.Errorf("expected a %s with the content, got %q (%s)", expected, result.Content, result.CacheStatus)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+
+	// no-cache revalidates the fresh response
+	result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Cache: CacheNoCache})
+	if err != nil || result.CacheStatus != CacheStatusRevalidated || result.Content != "cached content" {
+		t.Errorf("expected a revalidated response, got %+v, %v", result, err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("expected 1 conditional request, got %d", notModified.Load())
+	}
+}
+
+func TestHTTPCacheRevalidatesStaleResponses(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := createCachingServer("no-cache", &requests, &notModified)
+	defer server.Close()
+
+	fetcher := createCachingFetcher()
+	for range 2 {
+		if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if requests.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("expected the second request to be conditional, got %d requests and %d 304s", requests.Load(), notModified.Load())
+	}
+
+	// force-cache and only-if-cached use the stale response as is
+	for _, mode := range []CacheMode{CacheForceCache, CacheOnlyIfCached} {
+		result, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Cache: mode})
+		if err != nil || result.CacheStatus != CacheStatusHit {
+			t.Errorf("expected %s to serve the stored response, got %+v, %v", mode, result, err)
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected no further requests, got %d", requests.Load())
+	}
+}
+
+func TestHTTPCacheOnlyIfCached(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := createCachingServer("no-store", &requests, &notModified)
+	defer server.Close()
+
+	fetcher := createCachingFetcher()
+	if _, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The no-store response was not kept
+	_, err := fetcher.Fetch(context.Background(), &FetchRequest{URL: server.URL, Cache: CacheOnlyIfCached})
+	if !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected only-if-cached not to contact the server, got %d requests", requests.Load())
+	}
+}
+
+func TestParseCacheMode(t *testing.T) {
+	if mode, err := ParseCacheMode(""); err != nil || mode != CacheDefault {
+		t.Errorf("expected the default mode, got %q, %v", mode, err)
+	}
+	if mode, err := ParseCacheMode("force-cache"); err != nil || mode != CacheForceCache {
+		t.Errorf("expected force-cache, got %q, %v", mode, err)
+	}
+	if _, err := ParseCacheMode("reload"); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,82 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// schedulerPruneThreshold is the number of tracked hosts above which idle hosts are forgotten
+const schedulerPruneThreshold = 1024
+
+// hostScheduler spaces out requests to the same host. Callers reserve slots in
+// arrival order, so waiting callers are served first come, first served.
+type hostScheduler struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+	now  func() time.Time
+}
+
+// newHostScheduler creates an empty scheduler
+func newHostScheduler() *hostScheduler {
+	return &hostScheduler{
+		next: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// wait blocks until the caller may contact the host, keeping at least interval
+// between consecutive requests. It returns how long the caller waited, or the
+// context's error if it was cancelled first.
+func (s *hostScheduler) wait(ctx context.Context, host string, interval time.Duration) (time.Duration, error) {
+	if interval <= 0 {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	if len(s.next) >= schedulerPruneThreshold {
+		s.pruneLocked(now)
+	}
+	slot := now
+	if next, ok := s.next[host]; ok && next.After(slot) {
+		slot = next
+	}
+	s.next[host] = slot.Add(interval)
+	s.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		s.release(host, slot, interval)
+		return 0, ctx.Err()
+	}
+}
+
+// release gives back an abandoned slot if no later caller has reserved one after it
+func (s *hostScheduler) release(host string, slot time.Time, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next, ok := s.next[host]; ok && next.Equal(slot.Add(interval)) {
+		s.next[host] = slot
+	}
+}
+
+// pruneLocked forgets hosts whose next slot has already passed. The caller must hold s.mu.
+func (s *hostScheduler) pruneLocked(now time.Time) {
+	for host, next := range s.next {
+		if !next.After(now) {
+			delete(s.next, host)
+		}
+	}
+}
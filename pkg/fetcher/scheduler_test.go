@@ -0,0 +1,103 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSpacesRequests(t *testing.T) {
+	scheduler := newHostScheduler()
+	interval := 50 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := scheduler.wait(context.Background(), "example.com", interval); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("expected at least %s between three requests, got %s", 2*interval, elapsed)
+	}
+}
+
+func TestSchedulerHostsAreIndependent(t *testing.T) {
+	scheduler := newHostScheduler()
+
+	scheduler.wait(context.Background(), "a.example.com", time.Hour)
+	delay, err := scheduler.wait(context.Background(), "b.example.com", time.Hour)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delay != 0 {
+		t.Errorf("expected no delay for a different host, got %s", delay)
+	}
+}
+
+func TestSchedulerZeroInterval(t *testing.T) {
+	scheduler := newHostScheduler()
+
+	for i := 0; i < 3; i++ {
+		delay, err := scheduler.wait(context.Background(), "example.com", 0)
+		if err != nil || delay != 0 {
+			t.Errorf("expected no delay, got %s (err %v)", delay, err)
+		}
+	}
+}
+
+func TestSchedulerIsFIFO(t *testing.T) {
+	scheduler := newHostScheduler()
+	interval := 20 * time.Millisecond
+
+	// Occupy the first slot so the following callers queue up
+	scheduler.wait(context.Background(), "example.com", interval)
+
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scheduler.wait(context.Background(), "example.com", interval)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}(i)
+		// Ensure reservations are made in a known order
+		time.Sleep(2 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("expected callers to be served in arrival order, got %v", order)
+		}
+	}
+}
+
+func TestSchedulerRespectsContext(t *testing.T) {
+	scheduler := newHostScheduler()
+
+	scheduler.wait(context.Background(), "example.com", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := scheduler.wait(ctx, "example.com", time.Hour)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// The abandoned slot is released for the next caller
+	scheduler.mu.Lock()
+	next := scheduler.next["example.com"]
+	scheduler.mu.Unlock()
+	if remaining := time.Until(next); remaining > time.Hour+time.Second {
+		t.Errorf("expected abandoned slot to be released, next slot in %s", remaining)
+	}
+}
@@ -0,0 +1,169 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// Sitemap traversal limits
+const (
+	// maxSitemapDepth is the number of sitemap indexes followed below a discovered sitemap
+	maxSitemapDepth = 3
+	// maxSitemapFiles caps the number of sitemaps read for one request
+	maxSitemapFiles = 50
+)
+
+// SitemapRequest holds the parameters for listing the URLs of a site's sitemaps
+type SitemapRequest struct {
+	// URL is a sitemap, or any page of a site whose sitemaps are discovered
+	// from robots.txt, falling back to /sitemap.xml
+	URL string
+	// PathPrefix keeps only the URLs whose path starts with it
+	PathPrefix string
+	// Since keeps only the URLs modified at or after it; URLs without a lastmod are dropped
+	Since *time.Time
+	// Limit caps the number of URLs returned, if positive
+	Limit int
+}
+
+// SitemapResult holds the URLs listed by a site's sitemaps
+type SitemapResult struct {
+	// Sitemaps are the sitemaps that were read, indexes included
+	Sitemaps []string
+	URLs     []processor.SitemapURL
+	// Total is the number of matching URLs, including those beyond the limit
+	Total int
+	// Errors describes the sitemaps that could not be read
+	Errors []string
+}
+
+// sitemapTarget is a queued sitemap with the number of indexes above it
+type sitemapTarget struct {
+	url   string
+	depth int
+}
+
+// Sitemap reads the sitemaps of a URL, following sitemap indexes breadth
+// first, and lists the deduplicated URLs matching the request's filters.
+// Sitemaps that fail are reported in the result; it is an error only if none
+// could be read.
+func (f *HTTPFetcher) Sitemap(ctx context.Context, req *SitemapRequest) (*SitemapResult, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", req.URL)
+	}
+
+	var queue []sitemapTarget
+	seen := make(map[string]bool)
+	for _, root := range f.sitemapRoots(ctx, target) {
+		if !seen[root] {
+			seen[root] = true
+			queue = append(queue, sitemapTarget{url: root})
+		}
+	}
+
+	result := &SitemapResult{}
+	listed := make(map[string]bool)
+	for len(queue) > 0 && len(result.Sitemaps)+len(result.Errors) < maxSitemapFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("reading the sitemaps of %s interrupted: %w", req.URL, err)
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+
+		sitemap, err := f.readSitemap(ctx, current.url)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", current.url, err))
+			continue
+		}
+		result.Sitemaps = append(result.Sitemaps, current.url)
+
+		for _, child := range sitemap.Sitemaps {
+			if current.depth < maxSitemapDepth && !seen[child] {
+				seen[child] = true
+				queue = append(queue, sitemapTarget{url: child, depth: current.depth + 1})
+			}
+		}
+		for _, entry := range sitemap.URLs {
+			if listed[entry.URL] || !req.matches(entry) {
+				continue
+			}
+			listed[entry.URL] = true
+			result.Total++
+			if req.Limit <= 0 || len(result.URLs) < req.Limit {
+				result.URLs = append(result.URLs, entry)
+			}
+		}
+	}
+
+	if len(result.Sitemaps) == 0 {
+		return nil, fmt.Errorf("no sitemap could be read for %s: %s", req.URL, strings.Join(result.Errors, "; "))
+	}
+
+	log.Printf("Read %d sitemaps for %s: %d matching URLs, %d failures",
+		len(result.Sitemaps), req.URL, result.Total, len(result.Errors))
+	return result, nil
+}
+
+// sitemapRoots returns the URL itself if it looks like a sitemap, and otherwise
+// the sitemaps robots.txt lists for its host or the conventional /sitemap.xml
+func (f *HTTPFetcher) sitemapRoots(ctx context.Context, target *url.URL) []string {
+	path := strings.ToLower(target.Path)
+	if strings.Contains(path, "sitemap") || strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz") {
+		return []string{target.String()}
+	}
+
+	sitemaps, err := f.robotsChecker.Sitemaps(ctx, target.String())
+	if err != nil {
+		log.Printf("Failed to discover sitemaps from robots.txt for %s: %v", target, err)
+	}
+	if len(sitemaps) > 0 {
+		return sitemaps
+	}
+
+	origin := url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/sitemap.xml"}
+	return []string{origin.String()}
+}
+
+// readSitemap downloads and parses a sitemap
+func (f *HTTPFetcher) readSitemap(ctx context.Context, sitemapURL string) (*processor.Sitemap, error) {
+	_, body, err := f.download(ctx, sitemapURL, CacheDefault)
+	if err != nil {
+		return nil, err
+	}
+	return processor.ParseSitemap(body, processor.SitemapOptions{
+		MaxSize:  f.maxBodySize,
+		Truncate: f.oversizePolicy == OversizeTruncate,
+	})
+}
+
+// matches reports whether a sitemap URL passes the request's path and date filters
+func (req *SitemapRequest) matches(entry processor.SitemapURL) bool {
+	if req.PathPrefix != "" {
+		parsed, err := url.Parse(entry.URL)
+		if err != nil {
+			return false
+		}
+		path := parsed.Path
+		if path == "" {
+			path = "/"
+		}
+		if !strings.HasPrefix(path, "/"+strings.TrimPrefix(req.PathPrefix, "/")) {
+			return false
+		}
+	}
+	if req.Since != nil {
+		modified := processor.ParseLastMod(entry.LastMod)
+		if modified.IsZero() || modified.Before(*req.Since) {
+			return false
+		}
+	}
+	return true
+}
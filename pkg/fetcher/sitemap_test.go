@@ -0,0 +1,181 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createSitemapServer serves a sitemap index listed in robots.txt, with a
+// plain, a gzipped and a missing child sitemap
+func createSitemapServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "User-agent: *\nDisallow:\n\nSitemap: http://%s/sitemap_index.xml\n", r.Host)
+	})
+	mux.HandleFunc("/sitemap_index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>http://%[1]s/docs.xml</loc></sitemap>
+<sitemap><loc>http://%[1]s/blog.xml.gz</loc></sitemap>
+<sitemap><loc>http://%[1]s/missing.xml</loc></sitemap>
+<sitemap><loc>http://%[1]s/sitemap_index.xml</loc></sitemap>
+</sitemapindex>`, r.Host)
+	})
+	mux.HandleFunc("/docs.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>http://%[1]s/docs/</loc><lastmod>2024-03-01</lastmod><priority>1.0</priority></url>
+<url><loc>http://%[1]s/docs/install</loc><lastmod>2023-01-01</lastmod></url>
+<url><loc>http://%[1]s/docs/faq</loc></url>
+</urlset>`, r.Host)
+	})
+	mux.HandleFunc("/blog.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		fmt.Fprintf(writer, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>http://%[1]s/blog/first</loc><lastmod>2024-06-01T12:00:00Z</lastmod></url>
+<url><loc>http://%[1]s/docs/</loc><lastmod>2024-03-01</lastmod></url>
+</urlset>`, r.Host)
+		writer.Close()
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(compressed.Bytes())
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSitemapDiscovery(t *testing.T) {
+	server := createSitemapServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	result, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/docs/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Sitemaps) != 3 || !strings.HasSuffix(result.Sitemaps[2], "/blog.xml.gz") {
+		t.Errorf("expected the index and two children to be read, got %v", result.Sitemaps)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "/missing.xml") {
+		t.Errorf("expected the missing sitemap to be reported, got %v", result.Errors)
+	}
+
+	var listed []string
+	for _, entry := range result.URLs {
+		listed = append(listed, strings.TrimPrefix(entry.URL, server.URL))
+	}
+	if strings.Join(listed, " ") != "/docs/ /docs/install /docs/faq /blog/first" || result.Total != 4 {
+		t.Errorf("expected four deduplicated URLs, got %v (total %d)", listed, result.Total)
+	}
+	if result.URLs[0].Priority == nil || *result.URLs[0].Priority != 1 {
+		t.Errorf("expected the priority to be kept, got %+v", result.URLs[0])
+	}
+}
+
+func TestSitemapFilters(t *testing.T) {
+	server := createSitemapServer()
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      SitemapRequest
+		expected string
+		total    int
+	}{
+		{"path prefix", SitemapRequest{PathPrefix: "docs/"}, "/docs/ /docs/install /docs/faq", 3},
+		{"since", SitemapRequest{Since: &since}, "/docs/ /blog/first", 2},
+		{"limit", SitemapRequest{PathPrefix: "/docs", Limit: 1}, "/docs/", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.URL = server.URL + "/sitemap_index.xml"
+			result, err := fetcher.Sitemap(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var listed []string
+			for _, entry := range result.URLs {
+				listed = append(listed, strings.TrimPrefix(entry.URL, server.URL))
+			}
+			if strings.Join(listed, " ") != tt.expected || result.Total != tt.total {
+				t.Errorf("expected %s (total %d), got %v (total %d)", tt.expected, tt.total, listed, result.Total)
+			}
+		})
+	}
+}
+
+func TestSitemapFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("http://example.com/a\nhttp://example.com/b\n"))
+	}))
+	defer server.Close()
+
+	fetcher := createTestFetcher()
+	result, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/about"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.URLs) != 2 || result.Sitemaps[0] != server.URL+"/sitemap.xml" {
+		t.Errorf("expected /sitemap.xml to be read, got %+v", result)
+	}
+
+	if _, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/other-sitemap.xml"}); err == nil {
+		t.Error("expected an error when no sitemap can be read")
+	}
+	if _, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: "ftp://example.com/"}); err == nil {
+		t.Error("expected an error for a non-HTTP URL")
+	}
+}
+
+func TestSitemapGzipBodySizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		for i := range 1000 {
+			fmt.Fprintf(writer, "http://example.com/page/%d\n", i)
+		}
+		writer.Close()
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	// The compressed sitemap fits within the limit, but not once decompressed
+	fetcher := createTestFetcher()
+	WithMaxBodySize(10*1024, OversizeError)(fetcher)
+	_, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/sitemap.txt.gz"})
+	if err == nil || !strings.Contains(err.Error(), "exceeds 10240 bytes") {
+		t.Errorf("expected the decompressed sitemap to exceed the body size limit, got %v", err)
+	}
+
+	WithMaxBodySize(10*1024, OversizeTruncate)(fetcher)
+	result, err := fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/sitemap.txt.gz"})
+	if err != nil || result.Total == 0 || result.Total >= 1000 {
+		t.Errorf("expected the URLs within the limit, got %+v, %v", result, err)
+	}
+
+	WithMaxBodySize(0, OversizeError)(fetcher)
+	result, err = fetcher.Sitemap(context.Background(), &SitemapRequest{URL: server.URL + "/sitemap.txt.gz"})
+	if err != nil || result.Total != 1000 {
+		t.Errorf("expected no limit to keep every URL, got %+v, %v", result, err)
+	}
+}
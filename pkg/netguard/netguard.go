@@ -0,0 +1,176 @@
+// Package netguard provides dial-time protection against server-side request forgery.
+package netguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes lists address ranges that must never be reached unless explicitly allowed
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("10.0.0.0/8"),      // RFC 1918 private
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link-local, including cloud metadata endpoints
+	netip.MustParsePrefix("172.16.0.0/12"),   // RFC 1918 private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // Deprecated 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // RFC 1918 private
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved and broadcast
+	netip.MustParsePrefix("::/128"),          // Unspecified
+	netip.MustParsePrefix("::1/128"),         // Loopback
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("2001::/32"),       // Teredo, may tunnel to private IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, may tunnel to private IPv4
+	netip.MustParsePrefix("fc00::/7"),        // Unique local
+	netip.MustParsePrefix("fe80::/10"),       // Link-local
+	netip.MustParsePrefix("ff00::/8"),        // Multicast
+}
+
+// nat64Prefix is the well-known NAT64 prefix, whose addresses embed an IPv4 destination
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// BlockedAddressError is returned when a connection to a disallowed address is attempted
+type BlockedAddressError struct {
+	Addr netip.Addr
+}
+
+// Error implements the error interface
+func (e *BlockedAddressError) Error() string {
+	return fmt.Sprintf("connection to %s is blocked: address is private, loopback or otherwise non-public", e.Addr)
+}
+
+// Guard decides which IP addresses outbound connections may reach
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// NewGuard creates a guard that blocks non-public addresses except those within the allowed prefixes
+func NewGuard(allowed []netip.Prefix) *Guard {
+	return &Guard{
+		allowed: allowed,
+	}
+}
+
+// ParseCIDRs parses a list of CIDR strings into prefixes. Bare IP addresses are
+// accepted and treated as single-host prefixes.
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %v", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// IsAllowed reports whether a connection to the given address is permitted
+func (g *Guard) IsAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	// Judge NAT64 addresses by the IPv4 destination they translate to
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		addr = netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+	}
+
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if !addr.IsGlobalUnicast() {
+		return false
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Control is a net.Dialer control function that rejects connections to blocked
+// addresses. It runs after name resolution, immediately before connecting, so it
+// sees the address actually being dialed and cannot be bypassed by DNS rebinding
+// or redirects.
+func (g *Guard) Control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("connection to %s is blocked: unable to parse address: %v", address, err)
+	}
+
+	if !g.IsAllowed(addrPort.Addr()) {
+		return &BlockedAddressError{Addr: addrPort.Addr().Unmap()}
+	}
+
+	return nil
+}
+
+// DialContext dials the given address, rejecting blocked destinations
+func (g *Guard) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.Control,
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// CheckHost resolves a host name or IP literal and rejects it if any of its
+// addresses is blocked. It is meant for requests sent through a proxy, where
+// the dial-time check only sees the proxy's address. The proxy resolves the
+// name again, so this does not protect against DNS rebinding.
+func (g *Guard) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		if !g.IsAllowed(addr) {
+			return &BlockedAddressError{Addr: addr.Unmap()}
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("connection to %s is blocked: unable to resolve host: %v", host, err)
+	}
+	for _, addr := range addrs {
+		if !g.IsAllowed(addr) {
+			return &BlockedAddressError{Addr: addr.Unmap()}
+		}
+	}
+
+	return nil
+}
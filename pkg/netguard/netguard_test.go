@@ -0,0 +1,151 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsAllowed(t *testing.T) {
+	guard := NewGuard(nil)
+
+	tests := []struct {
+		name     string
+		addr     string
+		expected bool
+	}{
+		{"public IPv4", "93.184.216.34", true},
+		{"public IPv6", "2606:2800:220:1:248:1893:25c8:1946", true},
+		{"loopback", "127.0.0.1", false},
+		{"loopback range", "127.1.2.3", false},
+		{"IPv6 loopback", "::1", false},
+		{"cloud metadata", "169.254.169.254", false},
+		{"RFC 1918 10/8", "10.1.2.3", false},
+		{"RFC 1918 172.16/12", "172.20.0.1", false},
+		{"RFC 1918 192.168/16", "192.168.1.1", false},
+		{"carrier-grade NAT", "100.64.0.1", false},
+		{"unspecified", "0.0.0.0", false},
+		{"IPv6 unspecified", "::", false},
+		{"broadcast", "255.255.255.255", false},
+		{"multicast", "224.0.0.1", false},
+		{"IPv6 unique local", "fd00::1", false},
+		{"IPv6 link-local", "fe80::1", false},
+		{"IPv4-mapped loopback", "::ffff:127.0.0.1", false},
+		{"IPv4-mapped public", "::ffff:93.184.216.34", true},
+		{"NAT64 private", "64:ff9b::a00:1", false},
+		{"NAT64 public", "64:ff9b::5db8:d822", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := guard.IsAllowed(netip.MustParseAddr(tt.addr))
+			if result != tt.expected {
+				t.Errorf("expected %v for %s, got %v", tt.expected, tt.addr, result)
+			}
+		})
+	}
+}
+
+func TestIsAllowedWithAllowlist(t *testing.T) {
+	allowed, err := ParseCIDRs([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	guard := NewGuard(allowed)
+
+	if !guard.IsAllowed(netip.MustParseAddr("10.20.30.40")) {
+		t.Error("expected allowlisted private address to be allowed")
+	}
+	if !guard.IsAllowed(netip.MustParseAddr("127.0.0.1")) {
+		t.Error("expected allowlisted single host to be allowed")
+	}
+	if guard.IsAllowed(netip.MustParseAddr("127.0.0.2")) {
+		t.Error("expected loopback address outside allowlist to be blocked")
+	}
+	if guard.IsAllowed(netip.MustParseAddr("192.168.0.1")) {
+		t.Error("expected private address outside allowlist to be blocked")
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := ParseCIDRs([]string{" 10.0.0.1/8 ", "", "fd00::/8", "::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"10.0.0.0/8", "fd00::/8", "::1/128"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("expected %d prefixes, got %d", len(expected), len(prefixes))
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != expected[i] {
+			t.Errorf("expected prefix %q, got %q", expected[i], prefix.String())
+		}
+	}
+
+	if _, err := ParseCIDRs([]string{"not-a-cidr"}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
+
+func TestDialContextBlocksLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{DialContext: NewGuard(nil).DialContext},
+	}
+
+	_, err := client.Get(server.URL)
+
+	var blockedErr *BlockedAddressError
+	if !errors.As(err, &blockedErr) {
+		t.Fatalf("expected BlockedAddressError, got %v", err)
+	}
+	if blockedErr.Addr.String() != "127.0.0.1" {
+		t.Errorf("expected blocked address 127.0.0.1, got %s", blockedErr.Addr)
+	}
+}
+
+func TestDialContextAllowsAllowlisted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	allowed, _ := ParseCIDRs([]string{"127.0.0.0/8"})
+	guard := NewGuard(allowed)
+
+	conn, err := guard.DialContext(context.Background(), "tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("expected allowlisted dial to succeed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestCheckHost(t *testing.T) {
+	guard := NewGuard(nil)
+
+	for _, host := range []string{"127.0.0.1", "::1", "[::1]", "localhost"} {
+		var blockedErr *BlockedAddressError
+		if err := guard.CheckHost(context.Background(), host); !errors.As(err, &blockedErr) {
+			t.Errorf("expected %s to be blocked, got %v", host, err)
+		}
+	}
+
+	if err := guard.CheckHost(context.Background(), "8.8.8.8"); err != nil {
+		t.Errorf("expected a public address to be allowed, got %v", err)
+	}
+
+	allowed, _ := ParseCIDRs([]string{"127.0.0.0/8"})
+	if err := NewGuard(allowed).CheckHost(context.Background(), "127.0.0.1"); err != nil {
+		t.Errorf("expected an allowlisted address to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package processor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// boundaryTolerance is the fraction of a window, as a divisor, that a
+// boundary-aware cut may give up to land on a structural boundary
+const boundaryTolerance = 5
+
+// codeBlock is a fenced code block within markdown content
+type codeBlock struct {
+	// start is the byte offset of the opening fence line
+	start int
+	// body is the byte offset just past the opening fence line
+	body int
+	// end is the byte offset just past the closing fence line, or the length
+	// of the content when the block is never closed
+	end int
+	// opener is the opening fence line, including any info string
+	opener string
+	// marker is the run of fence characters that closes the block
+	marker string
+}
+
+// contains reports whether offset falls strictly inside the block
+func (b codeBlock) contains(offset int) bool {
+	return b.start < offset && offset < b.end
+}
+
+// codeBlocks returns the fenced code blocks of content that open before limit
+func codeBlocks(content string, limit int) []codeBlock {
+	var blocks []codeBlock
+	var open *codeBlock
+
+	for pos := 0; pos < len(content); {
+		if open == nil && pos >= limit {
+			break
+		}
+
+		line, next := lineAt(content, pos)
+		if open == nil {
+			if marker, ok := fenceMarker(line); ok {
+				open = &codeBlock{start: pos, body: next, opener: line, marker: marker}
+			}
+		} else if closesFence(line, open.marker) {
+			open.end = next
+			blocks = append(blocks, *open)
+			open = nil
+		}
+		pos = next
+	}
+
+	if open != nil {
+		open.end = len(content)
+		blocks = append(blocks, *open)
+	}
+
+	return blocks
+}
+
+// boundaryCut moves the end of the byte window [startByte, endByte) back to the
+// best structural boundary within the tolerance. Block boundaries (paragraphs,
+// headings, list items, code blocks and tables) are preferred, then line breaks
+// outside tables, then spaces between words. Without any, endByte is returned.
+func boundaryCut(content string, startByte, endByte int, blocks []codeBlock) int {
+	if endByte <= startByte || endByte >= len(content) {
+		return endByte
+	}
+
+	minCut := max(endByte-(endByte-startByte)/boundaryTolerance, startByte+1)
+
+	lineCut := -1
+	for pos := lineStart(content, endByte); pos >= minCut; pos = lineStart(content, pos-1) {
+		block := codeBlockAt(blocks, pos)
+		if block != nil && pos == block.body {
+			// Never leave an empty code block behind
+			continue
+		}
+		if block == nil && !betweenTableRows(content, pos) && isBlockBoundary(content, pos, blocks) {
+			return pos
+		}
+		// Splitting a table between rows or closing a code fence early is
+		// better than splitting a word
+		if lineCut < 0 {
+			lineCut = pos
+		}
+	}
+	if lineCut >= 0 {
+		return lineCut
+	}
+
+	// Fall back to the last space so no word is split
+	for pos := endByte; pos >= minCut; {
+		r, size := utf8.DecodeLastRuneInString(content[:pos])
+		if unicode.IsSpace(r) {
+			return pos
+		}
+		pos -= size
+	}
+
+	return endByte
+}
+
+// fenceWindow returns the content between startByte and endByte, reopening a
+// code block the window starts inside of and closing one it ends inside of
+func fenceWindow(content string, startByte, endByte int, blocks []codeBlock) string {
+	text := content[startByte:endByte]
+
+	for _, block := range blocks {
+		reopened := block.body <= startByte && block.contains(startByte)
+		if reopened {
+			text = block.opener + "\n" + text
+		}
+		if block.contains(endByte) && (reopened || block.start >= startByte) {
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			text += block.marker
+		}
+	}
+
+	return text
+}
+
+// codeBlockAt returns the code block offset falls strictly inside of, if any
+func codeBlockAt(blocks []codeBlock, offset int) *codeBlock {
+	for i := range blocks {
+		if blocks[i].contains(offset) {
+			return &blocks[i]
+		}
+	}
+	return nil
+}
+
+// isBlockBoundary reports whether the line starting at pos begins a new markdown block
+func isBlockBoundary(content string, pos int, blocks []codeBlock) bool {
+	if strings.HasSuffix(content[:pos], "\n\n") {
+		return true
+	}
+	for _, block := range blocks {
+		if pos == block.start || pos == block.end {
+			return true
+		}
+	}
+
+	line, _ := lineAt(content, pos)
+	return isHeading(line) || isListItem(line) || isTableRow(line)
+}
+
+// betweenTableRows reports whether the line starting at pos continues a table
+func betweenTableRows(content string, pos int) bool {
+	if pos == 0 {
+		return false
+	}
+	line, _ := lineAt(content, pos)
+	previous, _ := lineAt(content, lineStart(content, pos-1))
+	return isTableRow(line) && isTableRow(previous)
+}
+
+// lineStart returns the byte offset of the start of the line containing offset
+func lineStart(content string, offset int) int {
+	return strings.LastIndexByte(content[:offset], '\n') + 1
+}
+
+// lineAt returns the line starting at pos, without its newline, and the offset of the next line
+func lineAt(content string, pos int) (string, int) {
+	end := strings.IndexByte(content[pos:], '\n')
+	if end < 0 {
+		return content[pos:], len(content)
+	}
+	return content[pos : pos+end], pos + end + 1
+}
+
+// fenceMarker returns the fence characters of a line that opens a code block
+func fenceMarker(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", false
+	}
+
+	rest := strings.TrimLeft(trimmed, trimmed[:1])
+	marker := trimmed[:len(trimmed)-len(rest)]
+	if len(marker) < 3 || (marker[0] == '`' && strings.Contains(rest, "`")) {
+		return "", false
+	}
+	return marker, true
+}
+
+// closesFence reports whether a line closes a code block opened with marker
+func closesFence(line, marker string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, marker[:1])
+	return len(trimmed)-len(rest) >= len(marker) && strings.TrimSpace(rest) == ""
+}
+
+// isHeading reports whether a line is an ATX heading
+func isHeading(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	rest := strings.TrimLeft(trimmed, "#")
+	level := len(trimmed) - len(rest)
+	return level >= 1 && level <= 6 && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
+
+// isListItem reports whether a line starts a bullet or ordered list item
+func isListItem(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(trimmed) >= 2 && strings.ContainsRune("-*+", rune(trimmed[0])) && trimmed[1] == ' ' {
+		return true
+	}
+
+	digits := strings.TrimLeft(trimmed, "0123456789")
+	n := len(trimmed) - len(digits)
+	return n >= 1 && n <= 9 && len(digits) >= 2 && (digits[0] == '.' || digits[0] == ')') && digits[1] == ' '
+}
+
+// isTableRow reports whether a line is a row of a pipe table
+func isTableRow(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "|")
+}
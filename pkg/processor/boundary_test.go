@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPaginateBoundaryAware(t *testing.T) {
+	processor := NewContentProcessor()
+
+	tests := []struct {
+		name        string
+		content     string
+		startIndex  int
+		maxLength   int
+		expected    string
+		expectedEnd int
+	}{
+		{
+			name:        "backs off to a paragraph",
+			content:     "First paragraph here.\n\nSecond paragraph is longer.",
+			maxLength:   28,
+			expected:    "First paragraph here.",
+			expectedEnd: 23,
+		},
+		{
+			name:        "backs off to a heading",
+			content:     "Some intro text\n# Heading\nBody text follows",
+			maxLength:   30,
+			expected:    "Some intro text\n# Heading",
+			expectedEnd: 26,
+		},
+		{
+			name:        "backs off to a list item",
+			content:     "Items:\n- one item with more words\n- two items here",
+			maxLength:   40,
+			expected:    "Items:\n- one item with more words",
+			expectedEnd: 34,
+		},
+		{
+			name:        "never splits a word",
+			content:     "alpha beta gamma delta",
+			maxLength:   13,
+			expected:    "alpha beta",
+			expectedEnd: 11,
+		},
+		{
+			name:        "backs off to the start of a table",
+			content:     "Intro paragraph text that goes on for quite a while here.\n| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |",
+			maxLength:   72,
+			expected:    "Intro paragraph text that goes on for quite a while here.",
+			expectedEnd: 58,
+		},
+		{
+			name:        "cuts a long table between rows",
+			content:     "| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |\n| 5 | 6 |\n| 7 | 8 |",
+			maxLength:   45,
+			expected:    "| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |",
+			expectedEnd: 40,
+		},
+		{
+			name:        "backs off to the start of a code block",
+			content:     "Intro paragraph text that runs on.\n```go\nfunc main() {}\n```\nAfter",
+			maxLength:   42,
+			expected:    "Intro paragraph text that runs on.",
+			expectedEnd: 35,
+		},
+		{
+			name:        "closes an open code fence",
+			content:     "```go\nline one\nline two\nline three\nline four\n```\n",
+			maxLength:   28,
+			expected:    "```go\nline one\nline two\n```",
+			expectedEnd: 24,
+		},
+		{
+			name:        "reopens a code fence on the next page",
+			content:     "```go\nline one\nline two\nline three\nline four\n```\n",
+			startIndex:  24,
+			maxLength:   100,
+			expected:    "```go\nline three\nline four\n```\n",
+			expectedEnd: 49,
+		},
+		{
+			name:        "keeps the cut without a nearby boundary",
+			content:     "abcdefghijklmnopqrstuvwxyz",
+			maxLength:   10,
+			expected:    "abcdefghij",
+			expectedEnd: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := processor.Paginate(tt.content, PageOptions{
+				StartIndex:    intPtr(tt.startIndex),
+				MaxLength:     intPtr(tt.maxLength),
+				BoundaryAware: true,
+			})
+
+			content, _, _ := strings.Cut(page.Content, "\n\n[Content truncated.")
+			if content != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, content)
+			}
+			if page.End != tt.expectedEnd {
+				t.Errorf("expected end %d, got %d", tt.expectedEnd, page.End)
+			}
+			if page.Truncated && !strings.Contains(page.Content, "start_index="+strconv.Itoa(page.End)) {
+				t.Errorf("expected footer to name the next start index, got %q", page.Content)
+			}
+		})
+	}
+}
+
+func TestPaginateBoundaryAwareTokens(t *testing.T) {
+	processor := NewContentProcessor()
+	content := "A first paragraph that runs for a while.\n\nAnother paragraph with several more words in it."
+
+	page := processor.Paginate(content, PageOptions{MaxLength: intPtr(12), Unit: UnitTokens, BoundaryAware: true})
+	if !strings.HasPrefix(page.Content, "A first paragraph that runs for a while.\n\n[Content truncated.") {
+		t.Errorf("expected cut at the paragraph break, got %q", page.Content)
+	}
+
+	next := processor.Paginate(content, PageOptions{StartIndex: intPtr(page.End), Unit: UnitTokens})
+	if !strings.HasPrefix(next.Content, "Another") {
+		t.Errorf("expected next page to start at the paragraph, got %q", next.Content)
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	content := "text\n```go\ncode\n```\n~~~~\nunterminated ```\n"
+
+	blocks := codeBlocks(content, len(content))
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 code blocks, got %d", len(blocks))
+	}
+	if blocks[0].start != 5 || blocks[0].end != 20 || blocks[0].opener != "```go" || blocks[0].marker != "```" {
+		t.Errorf("unexpected first block %+v", blocks[0])
+	}
+	if blocks[1].marker != "~~~~" || blocks[1].end != len(content) {
+		t.Errorf("expected second block to run to the end, got %+v", blocks[1])
+	}
+}
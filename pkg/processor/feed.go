@@ -0,0 +1,475 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// maxSummaryRunes caps the length of each entry summary in a feed digest
+const maxSummaryRunes = 500
+
+// feedMediaTypes are the media types advertised by feed links in HTML pages
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// feedDateLayouts are the date formats found in the wild in RSS, Atom and JSON feeds
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// feed is an RSS, Atom or JSON feed normalized to a common form
+type feed struct {
+	title       string
+	description string
+	entries     []feedEntry
+}
+
+// feedEntry is a normalized feed item
+type feedEntry struct {
+	title   string
+	link    string
+	date    time.Time
+	summary string
+	author  string
+}
+
+// rssFeed covers RSS 0.9x, 1.0 and 2.0; RSS 1.0 places items beside the channel
+type rssFeed struct {
+	Channel struct {
+		Title       string    `xml:"title"`
+		Description string    `xml:"description"`
+		Items       []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+// rssItem is an RSS item, including the common Dublin Core and content extensions
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// atomFeed is an Atom feed
+type atomFeed struct {
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// atomEntry is an Atom entry
+type atomEntry struct {
+	Title atomText `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Summary   atomText `xml:"summary"`
+	Content   atomText `xml:"content"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+// atomText is an Atom text construct, holding text, escaped HTML or inline XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the construct's content; XHTML is returned as markup
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// plain returns the construct as plain text, unescaping HTML titles such as "Q&amp;amp;A"
+func (t atomText) plain() string {
+	if t.Type == "html" {
+		return html.UnescapeString(t.String())
+	}
+	return t.String()
+}
+
+// jsonFeed is a JSON Feed, version 1 or 1.1
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Items       []struct {
+		URL           string `json:"url"`
+		ExternalURL   string `json:"external_url"`
+		Title         string `json:"title"`
+		Summary       string `json:"summary"`
+		ContentText   string `json:"content_text"`
+		ContentHTML   string `json:"content_html"`
+		DatePublished string `json:"date_published"`
+		DateModified  string `json:"date_modified"`
+		Author        *struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+	} `json:"items"`
+}
+
+// handleFeed renders an RSS, Atom or JSON feed as a markdown digest, newest
+// entries first, keeping only entries published since in.Since and at most
+// in.Limit of them
+func (p *ContentProcessor) handleFeed(_ context.Context, in *Input) (*Document, error) {
+	if in.Raw {
+		return &Document{Content: string(in.Body)}, nil
+	}
+
+	parsed, err := parseFeed(in.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+
+	return &Document{Title: parsed.title, Content: p.renderFeed(parsed, in.Since, in.Limit)}, nil
+}
+
+// isFeed reports whether a body holds an RSS, Atom or JSON feed
+func isFeed(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var header struct {
+			Version string `json:"version"`
+		}
+		return json.Unmarshal(trimmed, &header) == nil && strings.HasPrefix(header.Version, "https://jsonfeed.org/version/")
+	}
+
+	switch xmlRoot(body) {
+	case "rss", "RDF", "feed":
+		return true
+	}
+	return false
+}
+
+// xmlRoot returns the local name of the root element of an XML document
+func xmlRoot(body []byte) string {
+	dec := newXMLDecoder(body)
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// newXMLDecoder returns a lenient decoder for a body that is already UTF-8
+func newXMLDecoder(body []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return dec
+}
+
+// parseFeed parses and normalizes an RSS, Atom or JSON feed
+func parseFeed(body []byte) (*feed, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return parseJSONFeed(body)
+	}
+
+	switch xmlRoot(body) {
+	case "rss", "RDF":
+		var rss rssFeed
+		if err := newXMLDecoder(body).Decode(&rss); err != nil {
+			return nil, err
+		}
+		return normalizeRSS(&rss), nil
+	case "feed":
+		var atom atomFeed
+		if err := newXMLDecoder(body).Decode(&atom); err != nil {
+			return nil, err
+		}
+		return normalizeAtom(&atom), nil
+	default:
+		return nil, errors.New("not an RSS, Atom or JSON feed")
+	}
+}
+
+// normalizeRSS converts an RSS feed to the common form
+func normalizeRSS(rss *rssFeed) *feed {
+	result := &feed{title: strings.TrimSpace(rss.Channel.Title), description: strings.TrimSpace(rss.Channel.Description)}
+
+	for _, item := range append(rss.Channel.Items, rss.Items...) {
+		entry := feedEntry{
+			title:   strings.TrimSpace(item.Title),
+			link:    firstNonEmpty(item.Link, item.GUID),
+			date:    parseFeedDate(firstNonEmpty(item.PubDate, item.Date)),
+			summary: firstNonEmpty(item.Description, item.Content),
+			author:  firstNonEmpty(item.Creator, item.Author),
+		}
+		// A GUID is only a link when it looks like one
+		if !strings.HasPrefix(entry.link, "http://") && !strings.HasPrefix(entry.link, "https://") {
+			entry.link = strings.TrimSpace(item.Link)
+		}
+		result.entries = append(result.entries, entry)
+	}
+
+	return result
+}
+
+// normalizeAtom converts an Atom feed to the common form
+func normalizeAtom(atom *atomFeed) *feed {
+	result := &feed{title: atom.Title.plain(), description: atom.Subtitle.String()}
+
+	for _, item := range atom.Entries {
+		entry := feedEntry{
+			title:   item.Title.plain(),
+			date:    parseFeedDate(firstNonEmpty(item.Published, item.Updated)),
+			summary: firstNonEmpty(item.Summary.String(), item.Content.String()),
+		}
+		for _, link := range item.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				entry.link = link.Href
+				break
+			}
+		}
+		names := make([]string, 0, len(item.Authors))
+		for _, author := range item.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				names = append(names, name)
+			}
+		}
+		entry.author = strings.Join(names, ", ")
+		result.entries = append(result.entries, entry)
+	}
+
+	return result
+}
+
+// parseJSONFeed parses a JSON Feed and converts it to the common form
+func parseJSONFeed(body []byte) (*feed, error) {
+	var parsed jsonFeed
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(parsed.Version, "https://jsonfeed.org/version/") {
+		return nil, errors.New("not a JSON Feed")
+	}
+
+	result := &feed{title: strings.TrimSpace(parsed.Title), description: strings.TrimSpace(parsed.Description)}
+	for _, item := range parsed.Items {
+		entry := feedEntry{
+			title:   strings.TrimSpace(item.Title),
+			link:    firstNonEmpty(item.URL, item.ExternalURL),
+			date:    parseFeedDate(firstNonEmpty(item.DatePublished, item.DateModified)),
+			summary: firstNonEmpty(item.Summary, item.ContentHTML, item.ContentText),
+		}
+		names := make([]string, 0, len(item.Authors)+1)
+		if item.Author != nil && item.Author.Name != "" {
+			names = append(names, item.Author.Name)
+		}
+		for _, author := range item.Authors {
+			if author.Name != "" {
+				names = append(names, author.Name)
+			}
+		}
+		entry.author = strings.Join(names, ", ")
+		result.entries = append(result.entries, entry)
+	}
+
+	return result, nil
+}
+
+// renderFeed renders a feed as a markdown digest. Entries are sorted newest
+// first, with undated entries last in their original order.
+func (p *ContentProcessor) renderFeed(parsed *feed, since *time.Time, limit int) string {
+	entries := make([]feedEntry, 0, len(parsed.entries))
+	for _, entry := range parsed.entries {
+		if since != nil && (entry.date.IsZero() || entry.date.Before(*since)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].date.IsZero() || entries[j].date.IsZero() {
+			return !entries[i].date.IsZero() && entries[j].date.IsZero()
+		}
+		return entries[i].date.After(entries[j].date)
+	})
+	matched := len(entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	var b strings.Builder
+	if parsed.title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", parsed.title)
+	}
+	if parsed.description != "" {
+		fmt.Fprintf(&b, "%s\n\n", p.feedText(parsed.description))
+	}
+	fmt.Fprintf(&b, "Showing %d of %d entries", len(entries), len(parsed.entries))
+	if since != nil {
+		fmt.Fprintf(&b, " (%d since %s)", matched, since.UTC().Format(time.RFC3339))
+	}
+	b.WriteString(".\n")
+
+	for _, entry := range entries {
+		title := entry.title
+		if title == "" {
+			title = "(untitled)"
+		}
+		if entry.link != "" {
+			fmt.Fprintf(&b, "\n## [%s](%s)\n", title, entry.link)
+		} else {
+			fmt.Fprintf(&b, "\n## %s\n", title)
+		}
+
+		var byline []string
+		if !entry.date.IsZero() {
+			byline = append(byline, entry.date.UTC().Format("2006-01-02 15:04 MST"))
+		}
+		if entry.author != "" {
+			byline = append(byline, entry.author)
+		}
+		if len(byline) > 0 {
+			fmt.Fprintf(&b, "\n%s\n", strings.Join(byline, " · "))
+		}
+
+		if summary := p.feedText(entry.summary); summary != "" {
+			fmt.Fprintf(&b, "\n%s\n", summary)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// feedText converts an HTML or plain text summary to markdown, shortened to maxSummaryRunes
+func (p *ContentProcessor) feedText(summary string) string {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return ""
+	}
+
+	if markdown, err := p.htmlConverter.ConvertString(summary); err == nil {
+		summary = strings.TrimSpace(markdown)
+	}
+
+	if utf8.RuneCountInString(summary) > maxSummaryRunes {
+		summary = strings.TrimSpace(string([]rune(summary)[:maxSummaryRunes])) + "…"
+	}
+	return summary
+}
+
+// parseFeedDate parses a feed date, returning the zero time if it is missing or unrecognized
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// firstNonEmpty returns the first of values that is not blank, trimmed
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// DiscoverFeeds returns the absolute URLs of the feeds an HTML page links to with
+// <link rel="alternate">, in document order
+func DiscoverFeeds(body []byte, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var feeds []string
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return feeds
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) == "body" {
+				return feeds
+			}
+			if string(name) != "link" || !hasAttr {
+				continue
+			}
+
+			attrs := tagAttributes(tokenizer)
+			if !hasToken(attrs["rel"], "alternate") || !feedMediaTypes[strings.ToLower(strings.TrimSpace(attrs["type"]))] {
+				continue
+			}
+			if href, err := base.Parse(strings.TrimSpace(attrs["href"])); err == nil && attrs["href"] != "" {
+				feeds = append(feeds, href.String())
+			}
+		}
+	}
+}
+
+// tagAttributes collects the attributes of the current tag, keyed by lowercase name
+func tagAttributes(tokenizer *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, value, more := tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// hasToken reports whether a space-separated attribute value contains token, ignoring case
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
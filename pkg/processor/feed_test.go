@@ -0,0 +1,206 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssSample = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+  <title>Example Blog</title>
+  <description>Notes &amp; news</description>
+  <item>
+    <title>Older post</title>
+    <link>https://example.com/older</link>
+    <pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate>
+    <description>&lt;p&gt;The &lt;b&gt;first&lt;/b&gt; post.&lt;/p&gt;</description>
+    <dc:creator>Ada</dc:creator>
+  </item>
+  <item>
+    <title>Newer post</title>
+    <guid>https://example.com/newer</guid>
+    <pubDate>Fri, 01 Mar 2024 10:00:00 GMT</pubDate>
+    <description>Plain summary.</description>
+  </item>
+  <item>
+    <title>Undated post</title>
+    <link>https://example.com/undated</link>
+  </item>
+</channel>
+</rss>`
+
+const atomSample = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Log</title>
+  <entry>
+    <title type="html">Entry &amp;amp; more</title>
+    <link rel="self" href="https://example.com/self"/>
+    <link href="https://example.com/entry"/>
+    <updated>2024-02-01T08:30:00Z</updated>
+    <author><name>Grace</name></author>
+    <summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">An <em>inline</em> summary.</div></summary>
+  </entry>
+</feed>`
+
+const jsonFeedSample = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Notes",
+  "items": [
+    {"id": "1", "url": "https://example.com/1", "title": "One", "content_text": "Text one.", "date_published": "2024-01-05T00:00:00Z"},
+    {"id": "2", "url": "https://example.com/2", "title": "Two", "content_html": "<p>Text two.</p>",
+     "date_published": "2024-01-06T00:00:00Z", "authors": [{"name": "Linus"}]}
+  ]
+}`
+
+func TestHandleFeedRSS(t *testing.T) {
+	processor := NewContentProcessor()
+
+	doc, err := processor.Process(context.Background(), &Input{Body: []byte(rssSample), MediaType: "application/rss+xml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if doc.Title != "Example Blog" {
+		t.Errorf("expected title %q, got %q", "Example Blog", doc.Title)
+	}
+
+	expected := "# Example Blog\n\nNotes & news\n\nShowing 3 of 3 entries.\n\n" +
+		"## [Newer post](https://example.com/newer)\n\n2024-03-01 10:00 UTC\n\nPlain summary.\n\n" +
+		"## [Older post](https://example.com/older)\n\n2024-01-01 10:00 UTC · Ada\n\nThe **first** post.\n\n" +
+		"## [Undated post](https://example.com/undated)"
+	if doc.Content != expected {
+		t.Errorf("expected:\n%s\n\ngot:\n%s", expected, doc.Content)
+	}
+}
+
+func TestHandleFeedAtom(t *testing.T) {
+	processor := NewContentProcessor()
+
+	// Served as generic XML, the feed is still recognized
+	doc, err := processor.Process(context.Background(), &Input{Body: []byte(atomSample), MediaType: "application/xml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Atom Log",
+		"## [Entry & more](https://example.com/entry)",
+		"2024-02-01 08:30 UTC · Grace",
+		"An _inline_ summary.",
+	} {
+		if !strings.Contains(doc.Content, want) {
+			t.Errorf("expected %q in:\n%s", want, doc.Content)
+		}
+	}
+}
+
+func TestHandleFeedJSON(t *testing.T) {
+	processor := NewContentProcessor()
+
+	doc, err := processor.Process(context.Background(), &Input{Body: []byte(jsonFeedSample), MediaType: "application/json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(doc.Content, "## [Two](https://example.com/2)\n\n2024-01-06 00:00 UTC · Linus\n\nText two.") {
+		t.Errorf("unexpected digest:\n%s", doc.Content)
+	}
+	if strings.Index(doc.Content, "[Two]") > strings.Index(doc.Content, "[One]") {
+		t.Errorf("expected newest entry first:\n%s", doc.Content)
+	}
+
+	// A JSON selection returns the document as JSON rather than as a feed
+	path, _ := ParseJSONPath("$.title")
+	doc, err = processor.Process(context.Background(), &Input{Body: []byte(jsonFeedSample), MediaType: "application/json", JSONPath: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Content != `"JSON Notes"` {
+		t.Errorf("expected the selected title, got %q", doc.Content)
+	}
+}
+
+func TestHandleFeedSinceAndLimit(t *testing.T) {
+	processor := NewContentProcessor()
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	doc, err := processor.Process(context.Background(), &Input{
+		Body:      []byte(rssSample),
+		MediaType: "application/rss+xml",
+		Since:     &since,
+		Limit:     1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(doc.Content, "Showing 1 of 3 entries (2 since 2024-01-01T00:00:00Z).") {
+		t.Errorf("unexpected summary line:\n%s", doc.Content)
+	}
+	if !strings.Contains(doc.Content, "Newer post") || strings.Contains(doc.Content, "Older post") || strings.Contains(doc.Content, "Undated") {
+		t.Errorf("expected only the newest entry:\n%s", doc.Content)
+	}
+}
+
+func TestHandleFeedRawAndInvalid(t *testing.T) {
+	processor := NewContentProcessor()
+
+	doc, err := processor.Process(context.Background(), &Input{Body: []byte(rssSample), MediaType: "application/rss+xml", Raw: true})
+	if err != nil || doc.Content != rssSample {
+		t.Errorf("expected the raw feed, got %q, %v", doc.Content, err)
+	}
+
+	if _, err := processor.Process(context.Background(), &Input{Body: []byte("<html></html>"), MediaType: "application/rss+xml"}); err == nil {
+		t.Error("expected an error for a body that is not a feed")
+	}
+
+	// XML that is not a feed is returned as text
+	doc, err = processor.Process(context.Background(), &Input{Body: []byte("<note>hi</note>"), MediaType: "text/xml"})
+	if err != nil || doc.Content != "<note>hi</note>" {
+		t.Errorf("expected the XML unchanged, got %q, %v", doc.Content, err)
+	}
+}
+
+func TestParseFeedDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T22:04:05Z"},
+		{"2 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"2024-03-01T10:00:00+02:00", "2024-03-01T08:00:00Z"},
+		{"2024-03-01", "2024-03-01T00:00:00Z"},
+		{"yesterday", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if date := parseFeedDate(tt.value); !date.IsZero() {
+			got = date.UTC().Format(time.RFC3339)
+		}
+		if got != tt.expected {
+			t.Errorf("parseFeedDate(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestDiscoverFeeds(t *testing.T) {
+	page := `<!DOCTYPE html><html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<LINK REL="Alternate" TYPE="application/atom+xml" HREF="https://other.example/atom">
+<link rel="alternate" type="text/html" hreflang="fr" href="/fr/">
+</head><body><link rel="alternate" type="application/feed+json" href="/late.json"></body></html>`
+
+	feeds := DiscoverFeeds([]byte(page), "https://example.com/blog/post")
+	expected := []string{"https://example.com/feed.xml", "https://other.example/atom"}
+	if strings.Join(feeds, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, feeds)
+	}
+
+	if feeds := DiscoverFeeds([]byte("<html><head></head></html>"), "https://example.com/"); len(feeds) != 0 {
+		t.Errorf("expected no feeds, got %v", feeds)
+	}
+}
@@ -0,0 +1,212 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Input is a fetched response body to be turned into text
+type Input struct {
+	// Body is the response body, already transcoded to UTF-8 if it is textual
+	Body []byte
+	// MediaType is the lowercase media type of the body, without parameters
+	MediaType string
+	// URL is the address the body was served from, used to resolve relative links
+	URL string
+	// Raw asks for the content without simplification
+	Raw bool
+	// Pages selects the pages of paged formats such as PDF; nil selects every page
+	Pages []PageRange
+	// JSONPath selects a sub-tree of JSON documents; nil selects the whole document
+	JSONPath *JSONPath
+	// JSONShape summarizes the keys, types and array lengths of JSON documents instead of their values
+	JSONShape bool
+	// Since drops feed entries published before it, and undated ones, when set
+	Since *time.Time
+	// Limit caps the number of feed entries returned; zero returns them all
+	Limit int
+	// MetadataHeader prefixes the content of HTML pages with a header listing their metadata
+	MetadataHeader bool
+	// AbsoluteURLs rewrites relative link and image URLs in HTML pages against URL and the page's <base>
+	AbsoluteURLs bool
+	// Selection converts only the matching elements of HTML pages instead of the article readability picks
+	Selection *Selection
+}
+
+// CacheKey identifies the document processing the input yields: a digest of
+// the body, media type, URL and every processing option
+func (in *Input) CacheKey() string {
+	h := sha256.New()
+	h.Write(in.Body)
+	fmt.Fprintf(h, "\x00%s\x00%s\x00%t\x00%v\x00%t\x00%d\x00%t\x00%t",
+		in.MediaType, in.URL, in.Raw, in.Pages, in.JSONShape, in.Limit, in.MetadataHeader, in.AbsoluteURLs)
+	if in.JSONPath != nil {
+		fmt.Fprintf(h, "\x00path:%s", in.JSONPath)
+	}
+	if in.Since != nil {
+		fmt.Fprintf(h, "\x00since:%s", in.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if in.Selection != nil {
+		fmt.Fprintf(h, "\x00selection:%s", in.Selection.key())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentHandler converts bodies of the media types it is registered for into a Document
+type ContentHandler interface {
+	Handle(ctx context.Context, in *Input) (*Document, error)
+}
+
+// ContentHandlerFunc adapts an ordinary function to a ContentHandler
+type ContentHandlerFunc func(ctx context.Context, in *Input) (*Document, error)
+
+// Handle calls f(ctx, in)
+func (f ContentHandlerFunc) Handle(ctx context.Context, in *Input) (*Document, error) {
+	return f(ctx, in)
+}
+
+// UnsupportedContentError is returned for binary content no handler is registered for
+type UnsupportedContentError struct {
+	MediaType string
+}
+
+// Error implements the error interface
+func (e *UnsupportedContentError) Error() string {
+	return fmt.Sprintf("unsupported binary content of type %s", e.MediaType)
+}
+
+// RegisterHandler registers a handler for a media type pattern. Patterns are an
+// exact media type such as "text/html", a structured syntax suffix such as
+// "application/*+json", a type wildcard such as "text/*", or "*/*". A later
+// registration for the same pattern replaces the earlier one.
+func (p *ContentProcessor) RegisterHandler(pattern string, handler ContentHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[strings.ToLower(pattern)] = handler
+}
+
+// Handler returns the handler for a media type, trying the exact type, then its
+// structured syntax suffix, then the type wildcard, then "*/*"
+func (p *ContentProcessor) Handler(mediaType string) (ContentHandler, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, pattern := range handlerPatterns(strings.ToLower(mediaType)) {
+		if handler, ok := p.handlers[pattern]; ok {
+			return handler, true
+		}
+	}
+	return nil, false
+}
+
+// Process converts a response body to text with the handler registered for its
+// media type. Bodies without a handler are treated as plain text if they sniff
+// as text, and rejected with an UnsupportedContentError otherwise.
+func (p *ContentProcessor) Process(ctx context.Context, in *Input) (*Document, error) {
+	handler, ok := p.Handler(in.MediaType)
+	if !ok {
+		if !strings.HasPrefix(http.DetectContentType(in.Body), "text/") {
+			return nil, &UnsupportedContentError{MediaType: in.MediaType}
+		}
+		handler = ContentHandlerFunc(handleText)
+	}
+
+	return handler.Handle(ctx, in)
+}
+
+// MediaType returns the media type of a Content-Type header, sniffing the body
+// when the header is missing or only says the content is arbitrary bytes
+func MediaType(contentType string, body []byte) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	return mediaType
+}
+
+// handlerPatterns lists the registry keys that may match a media type, most specific first
+func handlerPatterns(mediaType string) []string {
+	patterns := []string{mediaType}
+
+	mainType, subType, _ := strings.Cut(mediaType, "/")
+	if i := strings.LastIndexByte(subType, '+'); i >= 0 {
+		patterns = append(patterns, mainType+"/*"+subType[i:])
+	}
+
+	return append(patterns, mainType+"/*", "*/*")
+}
+
+// registerBuiltinHandlers registers the handlers for HTML, plain text, JSON,
+// XML, feeds and PDF. JSON and XML bodies that turn out to be feeds are
+// rendered as feeds.
+func (p *ContentProcessor) registerBuiltinHandlers() {
+	p.RegisterHandler("application/pdf", ContentHandlerFunc(handlePDF))
+
+	htmlHandler := ContentHandlerFunc(p.handleHTML)
+	p.RegisterHandler("text/html", htmlHandler)
+	p.RegisterHandler("application/xhtml+xml", htmlHandler)
+
+	feedHandler := ContentHandlerFunc(p.handleFeed)
+	for _, pattern := range []string{"application/rss+xml", "application/atom+xml", "application/feed+json"} {
+		p.RegisterHandler(pattern, feedHandler)
+	}
+
+	jsonHandler := p.sniffFeed(handleJSON)
+	for _, pattern := range []string{"application/json", "application/*+json", "text/json"} {
+		p.RegisterHandler(pattern, jsonHandler)
+	}
+
+	xmlHandler := p.sniffFeed(handleText)
+	for _, pattern := range []string{"application/xml", "application/*+xml", "text/xml"} {
+		p.RegisterHandler(pattern, xmlHandler)
+	}
+
+	textHandler := ContentHandlerFunc(handleText)
+	for _, pattern := range []string{"text/*", "application/javascript", "application/ecmascript"} {
+		p.RegisterHandler(pattern, textHandler)
+	}
+}
+
+// sniffFeed wraps a handler so that bodies which turn out to be feeds are
+// rendered as feeds, unless raw content or a JSON selection was asked for
+func (p *ContentProcessor) sniffFeed(next ContentHandlerFunc) ContentHandlerFunc {
+	return func(ctx context.Context, in *Input) (*Document, error) {
+		if !in.Raw && in.JSONPath == nil && !in.JSONShape && isFeed(in.Body) {
+			return p.handleFeed(ctx, in)
+		}
+		return next(ctx, in)
+	}
+}
+
+// handleHTML converts HTML to markdown, or returns it unchanged in raw mode,
+// along with the page's metadata
+func (p *ContentProcessor) handleHTML(ctx context.Context, in *Input) (*Document, error) {
+	if in.Raw {
+		return &Document{Content: string(in.Body), Metadata: ExtractMetadata(in.Body, in.URL)}, nil
+	}
+
+	doc, err := p.processHTML(ctx, string(in.Body), htmlOptions{
+		pageURL:      in.URL,
+		absoluteURLs: in.AbsoluteURLs,
+		selection:    in.Selection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if in.MetadataHeader {
+		doc.Content = doc.Metadata.Header() + doc.Content
+	}
+	return doc, nil
+}
+
+// handleText returns textual content as is
+func handleText(_ context.Context, in *Input) (*Document, error) {
+	return &Document{Content: string(in.Body)}, nil
+}
@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// namedHandler returns a handler whose output identifies it
+func namedHandler(name string) ContentHandler {
+	return ContentHandlerFunc(func(_ context.Context, _ *Input) (*Document, error) {
+		return &Document{Content: name}, nil
+	})
+}
+
+func TestHandlerLookup(t *testing.T) {
+	processor := NewContentProcessor()
+	processor.RegisterHandler("application/vnd.test", namedHandler("exact"))
+	processor.RegisterHandler("application/*+test", namedHandler("suffix"))
+	processor.RegisterHandler("image/*", namedHandler("wildcard"))
+
+	tests := []struct {
+		mediaType string
+		expected  string
+	}{
+		{"application/vnd.test", "exact"},
+		{"Application/VND.Test", "exact"},
+		{"application/vnd.other+test", "suffix"},
+		{"image/png", "wildcard"},
+		{"text/csv", "text/csv"},
+		{"application/ld+json", "application/ld+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			handler, ok := processor.Handler(tt.mediaType)
+			if !ok {
+				t.Fatalf("expected a handler for %s", tt.mediaType)
+			}
+			doc, err := handler.Handle(context.Background(), &Input{Body: []byte(tt.mediaType), MediaType: tt.mediaType})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.Content != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, doc.Content)
+			}
+		})
+	}
+
+	if _, ok := processor.Handler("application/zip"); ok {
+		t.Error("expected no handler for application/zip")
+	}
+
+	processor.RegisterHandler("*/*", namedHandler("fallback"))
+	if _, ok := processor.Handler("application/zip"); !ok {
+		t.Error("expected */* to match any media type")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	processor := NewContentProcessor()
+	ctx := context.Background()
+
+	doc, err := processor.Process(ctx, &Input{Body: []byte("<html><body><h1>Title</h1></body></html>"), MediaType: "text/html"})
+	if err != nil || !strings.Contains(doc.Content, "# Title") {
+		t.Errorf("expected HTML to be converted to markdown, got %+v, %v", doc, err)
+	}
+
+	doc, err = processor.Process(ctx, &Input{Body: []byte("<p>raw</p>"), MediaType: "text/html", Raw: true})
+	if err != nil || doc.Content != "<p>raw</p>" {
+		t.Errorf("expected raw HTML to be returned unchanged, got %+v, %v", doc, err)
+	}
+
+	doc, err = processor.Process(ctx, &Input{Body: []byte("key: value\n"), MediaType: "application/yaml"})
+	if err != nil || doc.Content != "key: value\n" {
+		t.Errorf("expected unregistered text to be returned as is, got %+v, %v", doc, err)
+	}
+
+	_, err = processor.Process(ctx, &Input{Body: []byte("\x89PNG\r\n\x1a\n\x00\x00"), MediaType: "image/png"})
+	var unsupported *UnsupportedContentError
+	if !errors.As(err, &unsupported) || unsupported.MediaType != "image/png" {
+		t.Errorf("expected UnsupportedContentError for image/png, got %v", err)
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"text/HTML; charset=utf-8", "", "text/html"},
+		{"application/json", "{}", "application/json"},
+		{"", "<!DOCTYPE html><html></html>", "text/html"},
+		{"application/octet-stream", "plain words", "text/plain"},
+		{"application/octet-stream", "\x00\x01\x02", "application/octet-stream"},
+		{"not a media type;;", "plain words", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		if got := MediaType(tt.contentType, []byte(tt.body)); got != tt.expected {
+			t.Errorf("MediaType(%q) = %q, expected %q", tt.contentType, got, tt.expected)
+		}
+	}
+}
+
+func TestInputCacheKey(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	path, _ := ParseJSONPath("$.items")
+	css, _ := ParseSelection("main", "", nil)
+	xpath, _ := ParseSelection("", "main", nil)
+	excluding, _ := ParseSelection("main", "", []string{".ad"})
+
+	base := Input{Body: []byte("<p>Hello</p>"), MediaType: "text/html", URL: "https://example.com/"}
+	variants := map[string]func(in *Input){
+		"body":          func(in *Input) { in.Body = []byte("<p>Bye</p>") },
+		"media type":    func(in *Input) { in.MediaType = "text/plain" },
+		"url":           func(in *Input) { in.URL = "https://example.com/other" },
+		"raw":           func(in *Input) { in.Raw = true },
+		"pages":         func(in *Input) { in.Pages = []PageRange{{First: 2, Last: 3}} },
+		"json path":     func(in *Input) { in.JSONPath = path },
+		"since":         func(in *Input) { in.Since = &since },
+		"limit":         func(in *Input) { in.Limit = 5 },
+		"metadata":      func(in *Input) { in.MetadataHeader = true },
+		"absolute urls": func(in *Input) { in.AbsoluteURLs = true },
+		"css":           func(in *Input) { in.Selection = css },
+		"xpath":         func(in *Input) { in.Selection = xpath },
+		"exclusions":    func(in *Input) { in.Selection = excluding },
+	}
+
+	same := base
+	same.Body = []byte("<p>Hello</p>")
+	if base.CacheKey() != same.CacheKey() {
+		t.Error("expected equal inputs to share a key")
+	}
+
+	keys := map[string]string{base.CacheKey(): "base"}
+	for name, vary := range variants {
+		in := base
+		vary(&in)
+		key := in.CacheKey()
+		if other, ok := keys[key]; ok {
+			t.Errorf("expected %s to change the key, got the key of %s", name, other)
+		}
+		keys[key] = name
+	}
+}
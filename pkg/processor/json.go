@@ -0,0 +1,292 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonIndent is the indentation used when pretty-printing JSON
+const jsonIndent = "  "
+
+// jsonObject is a decoded JSON object that keeps its members in document order
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// MarshalJSON encodes the object with its members in document order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := encodeJSON(key, "")
+		if err != nil {
+			return nil, err
+		}
+		value, err := encodeJSON(o.values[key], "")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// handleJSON pretty-prints JSON, optionally selecting a sub-tree with a JSONPath
+// expression and summarizing its shape instead of returning the values. Invalid
+// JSON is returned as is unless a selection or summary was asked for.
+func handleJSON(_ context.Context, in *Input) (*Document, error) {
+	if in.Raw {
+		return &Document{Content: string(in.Body)}, nil
+	}
+
+	if in.JSONPath == nil && !in.JSONShape {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, bytes.TrimSpace(in.Body), "", jsonIndent); err != nil {
+			return &Document{Content: string(in.Body)}, nil
+		}
+		return &Document{Content: buf.String()}, nil
+	}
+
+	value, err := decodeJSON(in.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if in.JSONPath != nil {
+		var ok bool
+		if value, ok = in.JSONPath.Select(value); !ok {
+			return nil, fmt.Errorf("json_path %q matched nothing", in.JSONPath)
+		}
+	}
+
+	if in.JSONShape {
+		shape := &jsonShape{}
+		shape.add(value)
+		return &Document{Content: shape.String()}, nil
+	}
+
+	content, err := encodeJSON(value, jsonIndent)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Content: string(content)}, nil
+}
+
+// decodeJSON decodes a JSON document, keeping object members in document
+// order and numbers in their original form
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	value, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+// decodeJSONValue decodes the next value from the decoder
+func decodeJSONValue(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{values: make(map[string]any)}
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			// Like encoding/json, the last duplicate member wins
+			if _, ok := object.values[key]; !ok {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		_, err = dec.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = dec.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
+
+// encodeJSON encodes a decoded value without escaping HTML characters,
+// indenting nested values when indent is not empty
+func encodeJSON(value any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonShape summarizes the structure of one or more JSON values: the kinds of
+// value seen, the members of objects and the lengths and elements of arrays
+type jsonShape struct {
+	seen  int
+	kinds map[string]bool
+
+	objects int
+	keys    []string
+	fields  map[string]*jsonShape
+
+	arrays         int
+	minLen, maxLen int
+	elem           *jsonShape
+}
+
+// jsonKinds lists value kinds in the order they are described
+var jsonKinds = []string{"object", "array", "string", "number", "boolean", "null"}
+
+// add merges a decoded value into the shape
+func (s *jsonShape) add(value any) {
+	s.seen++
+	if s.kinds == nil {
+		s.kinds = make(map[string]bool)
+	}
+
+	switch v := value.(type) {
+	case *jsonObject:
+		s.kinds["object"] = true
+		s.objects++
+		if s.fields == nil {
+			s.fields = make(map[string]*jsonShape)
+		}
+		for _, key := range v.keys {
+			field, ok := s.fields[key]
+			if !ok {
+				field = &jsonShape{}
+				s.fields[key] = field
+				s.keys = append(s.keys, key)
+			}
+			field.add(v.values[key])
+		}
+	case []any:
+		s.kinds["array"] = true
+		if s.arrays == 0 || len(v) < s.minLen {
+			s.minLen = len(v)
+		}
+		s.maxLen = max(s.maxLen, len(v))
+		s.arrays++
+		for _, elem := range v {
+			if s.elem == nil {
+				s.elem = &jsonShape{}
+			}
+			s.elem.add(elem)
+		}
+	case string:
+		s.kinds["string"] = true
+	case json.Number:
+		s.kinds["number"] = true
+	case bool:
+		s.kinds["boolean"] = true
+	default:
+		s.kinds["null"] = true
+	}
+}
+
+// String renders the shape as an indented, JSON-like outline
+func (s *jsonShape) String() string {
+	var b strings.Builder
+	s.write(&b, "")
+	return b.String()
+}
+
+// write renders the shape at the given indentation
+func (s *jsonShape) write(b *strings.Builder, indent string) {
+	first := true
+	for _, kind := range jsonKinds {
+		if !s.kinds[kind] {
+			continue
+		}
+		if !first {
+			b.WriteString(" | ")
+		}
+		first = false
+
+		switch kind {
+		case "object":
+			s.writeObject(b, indent)
+		case "array":
+			s.writeArray(b, indent)
+		default:
+			b.WriteString(kind)
+		}
+	}
+}
+
+// writeObject renders the members of the objects merged into the shape; members
+// missing from some of the objects are marked with a question mark
+func (s *jsonShape) writeObject(b *strings.Builder, indent string) {
+	if len(s.keys) == 0 {
+		b.WriteString("{}")
+		return
+	}
+
+	b.WriteString("{\n")
+	for i, key := range s.keys {
+		field := s.fields[key]
+		b.WriteString(indent + jsonIndent + strconv.Quote(key))
+		if field.seen < s.objects {
+			b.WriteString("?")
+		}
+		b.WriteString(": ")
+		field.write(b, indent+jsonIndent)
+		if i < len(s.keys)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+}
+
+// writeArray renders the lengths and element shape of the arrays merged into the shape
+func (s *jsonShape) writeArray(b *strings.Builder, indent string) {
+	if s.minLen == s.maxLen {
+		fmt.Fprintf(b, "array[%d]", s.maxLen)
+	} else {
+		fmt.Fprintf(b, "array[%d..%d]", s.minLen, s.maxLen)
+	}
+	if s.elem != nil {
+		b.WriteString(" of ")
+		s.elem.write(b, indent)
+	}
+}
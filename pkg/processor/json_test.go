@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHandleJSON(t *testing.T) {
+	body := `{"name":"gofetch","tags":["mcp","<fetch>"],"items":[{"id":1,"size":1.50},{"id":2,"note":null}]}`
+
+	tests := []struct {
+		name      string
+		body      string
+		jsonPath  string
+		jsonShape bool
+		raw       bool
+		expected  string
+		wantErr   bool
+	}{
+		{
+			name: "pretty-prints in document order",
+			body: body,
+			expected: `{
+  "name": "gofetch",
+  "tags": [
+    "mcp",
+    "<fetch>"
+  ],
+  "items": [
+    {
+      "id": 1,
+      "size": 1.50
+    },
+    {
+      "id": 2,
+      "note": null
+    }
+  ]
+}`,
+		},
+		{
+			name:     "raw mode returns the body unchanged",
+			body:     body,
+			raw:      true,
+			expected: body,
+		},
+		{
+			name:     "invalid JSON is returned as is",
+			body:     `{"broken":`,
+			expected: `{"broken":`,
+		},
+		{
+			name:     "definite path selects a sub-tree",
+			body:     body,
+			jsonPath: "$.items[0]",
+			expected: "{\n  \"id\": 1,\n  \"size\": 1.50\n}",
+		},
+		{
+			name:     "wildcard path returns every match",
+			body:     body,
+			jsonPath: "$.items[*].id",
+			expected: "[\n  1,\n  2\n]",
+		},
+		{
+			name:     "path without matches fails",
+			body:     body,
+			jsonPath: "$.missing",
+			wantErr:  true,
+		},
+		{
+			name:     "path on invalid JSON fails",
+			body:     `{"broken":`,
+			jsonPath: "$.broken",
+			wantErr:  true,
+		},
+		{
+			name:      "shape summarizes keys, types and array lengths",
+			body:      body,
+			jsonShape: true,
+			expected: `{
+  "name": string,
+  "tags": array[2] of string,
+  "items": array[2] of {
+    "id": number,
+    "size"?: number,
+    "note"?: null
+  }
+}`,
+		},
+		{
+			name:      "shape of a selection",
+			body:      `[[1,2],[3],"x"]`,
+			jsonPath:  "$[*]",
+			jsonShape: true,
+			expected:  "array[3] of array[1..2] of number | string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Input{Body: []byte(tt.body), MediaType: "application/json", Raw: tt.raw, JSONShape: tt.jsonShape}
+			if tt.jsonPath != "" {
+				path, err := ParseJSONPath(tt.jsonPath)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				in.JSONPath = path
+			}
+
+			doc, err := handleJSON(context.Background(), in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", doc.Content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.Content != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, doc.Content)
+			}
+		})
+	}
+}
+
+func TestJSONHandlerIsRegistered(t *testing.T) {
+	processor := NewContentProcessor()
+
+	for _, mediaType := range []string{"application/json", "application/problem+json"} {
+		doc, err := processor.Process(context.Background(), &Input{Body: []byte(`{"a":1}`), MediaType: mediaType})
+		if err != nil || doc.Content != "{\n  \"a\": 1\n}" {
+			t.Errorf("expected %s to be pretty-printed, got %+v, %v", mediaType, doc, err)
+		}
+	}
+}
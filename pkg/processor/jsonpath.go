@@ -0,0 +1,203 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled JSONPath expression. The supported subset covers the
+// root ($), child members (.name, ['name']), array indexes ([0], [-1]),
+// wildcards (.*, [*]) and recursive descent (..name, ..*).
+type JSONPath struct {
+	expr  string
+	steps []pathStep
+}
+
+// pathStep selects the children of a node by member name, array index or
+// wildcard, optionally from the node and all its descendants
+type pathStep struct {
+	name      string
+	index     *int
+	wildcard  bool
+	recursive bool
+}
+
+// ParseJSONPath compiles a JSONPath expression
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	path := &JSONPath{expr: expr}
+
+	rest := strings.TrimSpace(expr)
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		// Allow the root to be left out, as in "items[0].name"
+		rest = "." + rest
+	}
+	for rest != "" {
+		step, remaining, err := parsePathStep(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json_path %q: %v", expr, err)
+		}
+		path.steps = append(path.steps, step)
+		rest = remaining
+	}
+
+	return path, nil
+}
+
+// String returns the expression the path was compiled from
+func (p *JSONPath) String() string {
+	return p.expr
+}
+
+// definite reports whether the path selects at most one value
+func (p *JSONPath) definite() bool {
+	for _, step := range p.steps {
+		if step.wildcard || step.recursive {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePathStep parses the step at the start of s and returns the remainder
+func parsePathStep(s string) (pathStep, string, error) {
+	var step pathStep
+
+	switch {
+	case strings.HasPrefix(s, ".."):
+		step.recursive = true
+		s = s[2:]
+		if strings.HasPrefix(s, "[") {
+			return parseBracket(step, s)
+		}
+		return parseDotName(step, s)
+	case strings.HasPrefix(s, "."):
+		return parseDotName(step, s[1:])
+	case strings.HasPrefix(s, "["):
+		return parseBracket(step, s)
+	default:
+		return step, "", fmt.Errorf("unexpected %q", s)
+	}
+}
+
+// parseDotName parses a member name or wildcard following a dot
+func parseDotName(step pathStep, s string) (pathStep, string, error) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+
+	name := s[:end]
+	switch name {
+	case "":
+		return step, "", fmt.Errorf("missing member name")
+	case "*":
+		step.wildcard = true
+	default:
+		step.name = name
+	}
+
+	return step, s[end:], nil
+}
+
+// parseBracket parses a bracketed quoted name, index or wildcard
+func parseBracket(step pathStep, s string) (pathStep, string, error) {
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		end := strings.IndexByte(s[2:], s[1])
+		if end < 0 || !strings.HasPrefix(s[2+end+1:], "]") {
+			return step, "", fmt.Errorf("unterminated member name in %q", s)
+		}
+		step.name = s[2 : 2+end]
+		return step, s[2+end+2:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return step, "", fmt.Errorf("missing ] in %q", s)
+	}
+
+	inner := strings.TrimSpace(s[1:end])
+	if inner == "*" {
+		step.wildcard = true
+		return step, s[end+1:], nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return step, "", fmt.Errorf("invalid array index %q", inner)
+	}
+	step.index = &index
+	return step, s[end+1:], nil
+}
+
+// Select evaluates the path against a decoded JSON document. A definite path
+// returns the value it selects; other paths return every match as an array.
+func (p *JSONPath) Select(root any) (any, bool) {
+	nodes := []any{root}
+	for _, step := range p.steps {
+		var next []any
+		for _, node := range nodes {
+			candidates := []any{node}
+			if step.recursive {
+				candidates = descendants(node, nil)
+			}
+			for _, candidate := range candidates {
+				next = step.apply(candidate, next)
+			}
+		}
+		nodes = next
+	}
+
+	if p.definite() {
+		if len(nodes) == 0 {
+			return nil, false
+		}
+		return nodes[0], true
+	}
+	return nodes, len(nodes) > 0
+}
+
+// apply appends the children of node the step selects to matches
+func (s pathStep) apply(node any, matches []any) []any {
+	switch value := node.(type) {
+	case *jsonObject:
+		if s.wildcard {
+			for _, key := range value.keys {
+				matches = append(matches, value.values[key])
+			}
+		} else if child, ok := value.values[s.name]; ok && s.index == nil {
+			matches = append(matches, child)
+		}
+	case []any:
+		if s.wildcard {
+			matches = append(matches, value...)
+		} else if s.index != nil {
+			i := *s.index
+			if i < 0 {
+				i += len(value)
+			}
+			if i >= 0 && i < len(value) {
+				matches = append(matches, value[i])
+			}
+		}
+	}
+	return matches
+}
+
+// descendants appends node and every value nested in it, in document order
+func descendants(node any, all []any) []any {
+	all = append(all, node)
+	switch value := node.(type) {
+	case *jsonObject:
+		for _, key := range value.keys {
+			all = descendants(value.values[key], all)
+		}
+	case []any:
+		for _, child := range value {
+			all = descendants(child, all)
+		}
+	}
+	return all
+}
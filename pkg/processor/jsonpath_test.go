@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	valid := []string{"$", "", "$.a", "a.b", "$['a b'][0]", `$["a"]`, "$..id", "$..[0]", "$.*", "$[*]", "$[-1]"}
+	for _, expr := range valid {
+		if _, err := ParseJSONPath(expr); err != nil {
+			t.Errorf("expected %q to parse, got %v", expr, err)
+		}
+	}
+
+	invalid := []string{"$.", "$[", "$[abc]", "$['a'", "$x", "$..", "$['a'x"}
+	for _, expr := range invalid {
+		if _, err := ParseJSONPath(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestJSONPathSelect(t *testing.T) {
+	doc, err := decodeJSON([]byte(`{"store":{"book":[{"title":"A","price":8},{"title":"B","price":12}],"bike":{"price":20}},"a b":true}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+		found    bool
+	}{
+		{"$", "", true},
+		{"$.store.book[1].title", `"B"`, true},
+		{"$.store.book[-1].price", "12", true},
+		{"$['a b']", "true", true},
+		{"$.store.book[*].title", `["A","B"]`, true},
+		{"$..price", "[8,12,20]", true},
+		{"$.store.*.price", "[20]", true},
+		{"$.store.book[5]", "", false},
+		{"$.nothing[*]", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			path, err := ParseJSONPath(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			value, found := path.Select(doc)
+			if found != tt.found {
+				t.Fatalf("expected found %v, got %v", tt.found, found)
+			}
+			if !found || tt.expected == "" {
+				return
+			}
+
+			encoded, err := encodeJSON(value, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(encoded) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, encoded)
+			}
+		})
+	}
+}
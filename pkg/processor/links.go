@@ -0,0 +1,320 @@
+package processor
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxLinkContextRunes caps the surrounding text returned with each link
+const maxLinkContextRunes = 200
+
+// Page sections a link can appear in
+const (
+	SectionNav     = "nav"
+	SectionContent = "content"
+	SectionFooter  = "footer"
+)
+
+// linkFileTypes maps lowercase path extensions to the file type reported for links
+var linkFileTypes = map[string]string{
+	"":      "html",
+	".html": "html", ".htm": "html", ".xhtml": "html", ".php": "html", ".asp": "html", ".aspx": "html", ".jsp": "html",
+	".pdf": "pdf",
+	".png": "image", ".jpg": "image", ".jpeg": "image", ".gif": "image", ".webp": "image", ".svg": "image", ".avif": "image",
+	".mp3": "audio", ".ogg": "audio", ".wav": "audio", ".flac": "audio",
+	".mp4": "video", ".webm": "video", ".mov": "video", ".avi": "video",
+	".zip": "archive", ".tar": "archive", ".gz": "archive", ".tgz": "archive", ".bz2": "archive", ".xz": "archive",
+	".7z": "archive", ".rar": "archive",
+	".doc": "document", ".docx": "document", ".odt": "document", ".rtf": "document",
+	".xls": "spreadsheet", ".xlsx": "spreadsheet", ".ods": "spreadsheet", ".csv": "spreadsheet",
+	".ppt": "presentation", ".pptx": "presentation", ".odp": "presentation",
+	".json": "data", ".xml": "data", ".rss": "data", ".atom": "data", ".txt": "text", ".md": "text",
+}
+
+// Link is a hyperlink found on a page
+type Link struct {
+	// URL is absolute, without its fragment
+	URL string `json:"url"`
+	// Text is the anchor text, or the title or image alt text for links without text
+	Text string `json:"text,omitempty"`
+	// Context is the text of the block the link appears in
+	Context string `json:"context,omitempty"`
+	// Internal reports whether the link points to the page's own host
+	Internal bool `json:"internal"`
+	// Section is where on the page the link was found: nav, content or footer
+	Section string `json:"section"`
+	// FileType is the kind of resource the link points to, guessed from its extension
+	FileType string `json:"file_type"`
+}
+
+// LinkOptions filters the links returned by ExtractLinks
+type LinkOptions struct {
+	// Pattern keeps only the links whose absolute URL matches it
+	Pattern *regexp.Regexp
+	// SameHost keeps only internal links
+	SameHost bool
+}
+
+// ExtractLinks returns the HTTP links of an HTML page in document order,
+// resolved against pageURL and the page's <base> and deduplicated with their
+// fragments removed
+func ExtractLinks(body []byte, pageURL string, opts LinkOptions) []Link {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	base := documentBase(doc, pageURL)
+	if base == nil {
+		base = page
+	}
+
+	c := &linkCollector{base: base, page: page, opts: opts, links: []Link{}, seen: make(map[string]int)}
+	c.walk(doc, SectionContent)
+	return c.links
+}
+
+// linkCollector gathers the distinct links of a page
+type linkCollector struct {
+	base, page *url.URL
+	opts       LinkOptions
+	links      []Link
+	seen       map[string]int
+}
+
+// walk collects the links in a node and its descendants
+func (c *linkCollector) walk(n *html.Node, section string) {
+	if n.Type == html.ElementNode {
+		section = linkSection(n, section)
+		if n.DataAtom == atom.A || n.DataAtom == atom.Area {
+			if link, ok := newLink(n, c.base, c.page, section); ok && c.opts.keep(link) {
+				c.add(link)
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child, section)
+	}
+}
+
+// add records a link unless its URL was seen before, in which case only a
+// missing anchor text is filled in
+func (c *linkCollector) add(link Link) {
+	if i, ok := c.seen[link.URL]; ok {
+		if c.links[i].Text == "" {
+			c.links[i].Text = link.Text
+		}
+		return
+	}
+	c.seen[link.URL] = len(c.links)
+	c.links = append(c.links, link)
+}
+
+// keep reports whether a link passes the filters
+func (o LinkOptions) keep(link Link) bool {
+	if o.SameHost && !link.Internal {
+		return false
+	}
+	return o.Pattern == nil || o.Pattern.MatchString(link.URL)
+}
+
+// findBase returns the href of the document's first <base> element
+func findBase(n *html.Node) string {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Base {
+		if href := strings.TrimSpace(attr(n, "href")); href != "" {
+			return href
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := findBase(c); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// newLink builds the link for an <a> or <area> element, if it has an HTTP href
+// to another page
+func newLink(n *html.Node, base, page *url.URL, section string) (Link, bool) {
+	// In-page anchors point back at the page itself
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") {
+		return Link{}, false
+	}
+	target, err := base.Parse(href)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return Link{}, false
+	}
+	target.Fragment = ""
+	target.RawFragment = ""
+
+	text := collapseSpace(nodeText(n))
+	if text == "" {
+		text = collapseSpace(firstNonEmpty(attr(n, "title"), attr(n, "aria-label"), imageAlt(n)))
+	}
+
+	return Link{
+		URL:      target.String(),
+		Text:     text,
+		Context:  linkContext(n, text),
+		Internal: sameHost(target, page),
+		Section:  section,
+		FileType: linkFileType(target),
+	}, true
+}
+
+// linkSection returns the section an element and its descendants belong to,
+// judging from its tag, ARIA role and id and class names
+func linkSection(n *html.Node, parent string) string {
+	switch n.DataAtom {
+	case atom.Nav:
+		return SectionNav
+	case atom.Header:
+		// Headers of articles hold titles and bylines rather than site navigation
+		if !hasAncestor(n, atom.Article, atom.Main) {
+			return SectionNav
+		}
+	case atom.Footer:
+		return SectionFooter
+	case atom.Main, atom.Article:
+		return SectionContent
+	}
+
+	switch strings.ToLower(attr(n, "role")) {
+	case "navigation", "banner", "menubar", "menu":
+		return SectionNav
+	case "contentinfo":
+		return SectionFooter
+	case "main":
+		return SectionContent
+	}
+
+	for _, name := range strings.Fields(strings.ToLower(attr(n, "id") + " " + attr(n, "class"))) {
+		if strings.Contains(name, "footer") {
+			return SectionFooter
+		}
+		if isNavName(name) {
+			return SectionNav
+		}
+	}
+
+	return parent
+}
+
+// hasAncestor reports whether an element is nested in an element of one of the given types
+func hasAncestor(n *html.Node, types ...atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, t := range types {
+			if p.DataAtom == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNavName reports whether an id or class name marks a navigation block
+func isNavName(name string) bool {
+	switch name {
+	case "nav", "navbar", "navigation", "menu", "sidebar":
+		return true
+	}
+	return strings.HasPrefix(name, "breadcrumb") || strings.HasPrefix(name, "nav-") ||
+		strings.HasSuffix(name, "-nav") || strings.HasSuffix(name, "-menu")
+}
+
+// linkContext returns the text of the nearest block around a link, shortened
+// to maxLinkContextRunes around the anchor text. Links that make up their
+// whole block have no context.
+func linkContext(n *html.Node, text string) string {
+	block := n.Parent
+	for block != nil && block.Type == html.ElementNode && !isBlockElement(block) {
+		block = block.Parent
+	}
+	if block == nil || block.Type != html.ElementNode {
+		return ""
+	}
+
+	context := collapseSpace(nodeText(block))
+	if context == text {
+		return ""
+	}
+
+	runes := []rune(context)
+	if len(runes) <= maxLinkContextRunes {
+		return context
+	}
+
+	// Center the window on the anchor text
+	start := 0
+	if i := strings.Index(context, text); i >= 0 && text != "" {
+		center := utf8.RuneCountInString(context[:i]) + utf8.RuneCountInString(text)/2
+		start = max(0, min(center-maxLinkContextRunes/2, len(runes)-maxLinkContextRunes))
+	}
+	window := strings.TrimSpace(string(runes[start : start+maxLinkContextRunes]))
+	if start > 0 {
+		window = "…" + window
+	}
+	if start+maxLinkContextRunes < len(runes) {
+		window += "…"
+	}
+	return window
+}
+
+// isBlockElement reports whether an element starts a block of text
+func isBlockElement(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Li, atom.Td, atom.Th, atom.Dd, atom.Dt, atom.Blockquote, atom.Figcaption, atom.Caption,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Div, atom.Section, atom.Article, atom.Aside,
+		atom.Nav, atom.Header, atom.Footer, atom.Main, atom.Body, atom.Pre, atom.Address:
+		return true
+	}
+	return false
+}
+
+// imageAlt returns the alt text of the first image inside a link
+func imageAlt(n *html.Node) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Img {
+			return attr(c, "alt")
+		}
+		if alt := imageAlt(c); alt != "" {
+			return alt
+		}
+	}
+	return ""
+}
+
+// sameHost reports whether a link points to the page's host, treating a
+// leading "www." as insignificant
+func sameHost(target, page *url.URL) bool {
+	trim := func(host string) string {
+		return strings.TrimPrefix(strings.ToLower(host), "www.")
+	}
+	return trim(target.Hostname()) == trim(page.Hostname())
+}
+
+// linkFileType guesses the kind of resource a URL points to from its extension
+func linkFileType(target *url.URL) string {
+	ext := strings.ToLower(path.Ext(target.Path))
+	if fileType, ok := linkFileTypes[ext]; ok {
+		return fileType
+	}
+	return "other"
+}
+
+// collapseSpace trims a string and collapses its runs of whitespace into single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const linksPage = `<html><head><base href="https://example.com/docs/"></head><body>
+<header><nav><a href="/">Home</a> <a href="guide/">Guide</a></nav></header>
+<main>
+  <article>
+    <header><a href="https://example.com/authors/ada">Ada</a></header>
+    <p>Read the <a href="guide/#install">installation guide</a> before you start, or grab the
+       <a href="files/manual.PDF">manual</a>.</p>
+    <p><a href="guide/#usage"></a><a href="https://www.example.com/blog"><img alt="Blog" src="b.png"></a></p>
+    <p><a href="https://github.com/example/repo">https://github.com/example/repo</a></p>
+    <p><a href="mailto:ada@example.com">Mail</a> <a href="javascript:void(0)">Nothing</a> <a href="#top">Top</a></p>
+  </article>
+</main>
+<div class="site-footer"><a href="https://twitter.com/example">Follow us</a></div>
+</body></html>`
+
+func TestExtractLinks(t *testing.T) {
+	links := ExtractLinks([]byte(linksPage), "https://example.com/docs/start", LinkOptions{})
+
+	expected := []Link{
+		{URL: "https://example.com/", Text: "Home", Internal: true, Section: SectionNav, FileType: "html"},
+		{URL: "https://example.com/docs/guide/", Text: "Guide", Internal: true, Section: SectionNav, FileType: "html"},
+		{URL: "https://example.com/authors/ada", Text: "Ada", Internal: true, Section: SectionContent, FileType: "html"},
+	}
+	for i, want := range expected {
+		got := links[i]
+		got.Context = ""
+		if got != want {
+			t.Errorf("link %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	byURL := make(map[string]Link)
+	for _, link := range links {
+		if _, dup := byURL[link.URL]; dup {
+			t.Errorf("duplicate link %s", link.URL)
+		}
+		byURL[link.URL] = link
+	}
+
+	if len(links) != 7 {
+		t.Errorf("expected 7 distinct links, got %d: %+v", len(links), links)
+	}
+	if manual := byURL["https://example.com/docs/files/manual.PDF"]; manual.FileType != "pdf" ||
+		!strings.HasPrefix(manual.Context, "Read the installation guide before you start") {
+		t.Errorf("unexpected manual link: %+v", manual)
+	}
+	if blog := byURL["https://www.example.com/blog"]; blog.Text != "Blog" || !blog.Internal {
+		t.Errorf("expected the image alt text and www. to count as internal: %+v", blog)
+	}
+	if repo := byURL["https://github.com/example/repo"]; repo.Internal || repo.Context != "" {
+		t.Errorf("expected an external link without context: %+v", repo)
+	}
+	if follow := byURL["https://twitter.com/example"]; follow.Section != SectionFooter {
+		t.Errorf("expected a footer link: %+v", follow)
+	}
+	for _, link := range links {
+		if !strings.HasPrefix(link.URL, "https://") {
+			t.Errorf("expected only web links, got %s", link.URL)
+		}
+	}
+}
+
+func TestExtractLinksFilters(t *testing.T) {
+	links := ExtractLinks([]byte(linksPage), "https://example.com/docs/start", LinkOptions{SameHost: true})
+	for _, link := range links {
+		if !link.Internal {
+			t.Errorf("expected only internal links, got %s", link.URL)
+		}
+	}
+	if len(links) != 5 {
+		t.Errorf("expected 5 internal links, got %d", len(links))
+	}
+
+	links = ExtractLinks([]byte(linksPage), "https://example.com/docs/start", LinkOptions{Pattern: regexp.MustCompile(`/docs/`)})
+	if len(links) != 2 || links[0].URL != "https://example.com/docs/guide/" || links[1].FileType != "pdf" {
+		t.Errorf("expected the guide and the manual, got %+v", links)
+	}
+}
+
+func TestLinkContext(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 40)
+	page := `<p>` + long + `<a href="/x">target</a> ` + long + `</p>`
+
+	links := ExtractLinks([]byte(page), "https://example.com/", LinkOptions{})
+	if len(links) != 1 {
+		t.Fatalf("expected 1 link, got %d", len(links))
+	}
+	context := links[0].Context
+	if !strings.Contains(context, "target") || !strings.HasPrefix(context, "…") || !strings.HasSuffix(context, "…") {
+		t.Errorf("expected a window around the anchor, got %q", context)
+	}
+}
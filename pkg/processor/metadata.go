@@ -0,0 +1,397 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Metadata is the page-level metadata found in an HTML document's head and markup
+type Metadata struct {
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	Language     string `json:"language,omitempty"`
+	Favicon      string `json:"favicon,omitempty"`
+	SiteName     string `json:"site_name,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Image        string `json:"image,omitempty"`
+	// OpenGraph holds the OpenGraph properties, such as og:title or article:author, keyed by property
+	OpenGraph map[string]string `json:"open_graph,omitempty"`
+	// Twitter holds the Twitter card tags, such as twitter:card, keyed by name
+	Twitter map[string]string `json:"twitter,omitempty"`
+	// JSONLD holds the decoded JSON-LD objects, with top-level arrays flattened
+	JSONLD []any `json:"json_ld,omitempty"`
+	// Microdata holds the top-level schema.org microdata items
+	Microdata []*MicrodataItem `json:"microdata,omitempty"`
+}
+
+// MicrodataItem is an element with an itemscope attribute. Property values
+// are strings, or nested items for properties that have their own itemscope.
+type MicrodataItem struct {
+	Type       []string         `json:"type,omitempty"`
+	ID         string           `json:"id,omitempty"`
+	Properties map[string][]any `json:"properties,omitempty"`
+}
+
+// ExtractMetadata collects the metadata of an HTML page, resolving relative
+// URLs against pageURL when it is set
+func ExtractMetadata(body []byte, pageURL string) *Metadata {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return extractMetadata(doc, pageURL)
+}
+
+// openGraphPrefixes are the property prefixes of the OpenGraph protocol and its object types
+var openGraphPrefixes = []string{"og:", "article:", "book:", "profile:", "product:", "video:", "music:"}
+
+// metadataWalker accumulates metadata while walking a parsed HTML page
+type metadataWalker struct {
+	meta        *Metadata
+	base        *url.URL
+	title       string
+	description string
+}
+
+// extractMetadata collects the metadata of a parsed HTML page
+func extractMetadata(doc *html.Node, pageURL string) *Metadata {
+	base, _ := url.Parse(pageURL)
+	w := &metadataWalker{
+		meta: &Metadata{OpenGraph: make(map[string]string), Twitter: make(map[string]string)},
+		base: base,
+	}
+	w.walk(doc)
+	return w.finish()
+}
+
+// walk visits a node and its descendants in document order
+func (w *metadataWalker) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		w.visit(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+// visit records the metadata carried by an element
+func (w *metadataWalker) visit(n *html.Node) {
+	switch n.DataAtom {
+	case atom.Html:
+		w.meta.Language = attr(n, "lang")
+	case atom.Base:
+		if href := attr(n, "href"); href != "" && w.base != nil {
+			if resolved, err := w.base.Parse(href); err == nil {
+				w.base = resolved
+			}
+		}
+	case atom.Title:
+		if w.title == "" {
+			w.title = strings.TrimSpace(nodeText(n))
+		}
+	case atom.Meta:
+		w.description = w.meta.addMeta(n, w.description)
+	case atom.Link:
+		w.meta.addLink(n, w.base)
+	case atom.Script:
+		if strings.EqualFold(strings.TrimSpace(attr(n, "type")), "application/ld+json") {
+			w.meta.addJSONLD(nodeText(n))
+		}
+	}
+
+	// Top-level microdata items; nested ones are collected by their parent
+	if hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") {
+		w.meta.Microdata = append(w.meta.Microdata, microdataItem(n, w.base))
+	}
+}
+
+// finish fills the summary fields from the most specific source available:
+// OpenGraph, then Twitter cards, then plain HTML
+func (w *metadataWalker) finish() *Metadata {
+	m := w.meta
+	m.Title = firstNonEmpty(m.OpenGraph["og:title"], m.Twitter["twitter:title"], w.title)
+	m.Description = firstNonEmpty(m.OpenGraph["og:description"], m.Twitter["twitter:description"], w.description)
+	m.SiteName = m.OpenGraph["og:site_name"]
+	m.Type = m.OpenGraph["og:type"]
+	m.Image = resolveURL(w.base, firstNonEmpty(m.OpenGraph["og:image"], m.Twitter["twitter:image"]))
+	if m.CanonicalURL == "" {
+		m.CanonicalURL = resolveURL(w.base, m.OpenGraph["og:url"])
+	}
+	if m.Language == "" {
+		m.Language = strings.ReplaceAll(m.OpenGraph["og:locale"], "_", "-")
+	}
+
+	if len(m.OpenGraph) == 0 {
+		m.OpenGraph = nil
+	}
+	if len(m.Twitter) == 0 {
+		m.Twitter = nil
+	}
+	return m
+}
+
+// addMeta records an OpenGraph, Twitter card, description or language meta
+// tag, returning the plain description seen so far
+func (m *Metadata) addMeta(n *html.Node, description string) string {
+	content := strings.TrimSpace(attr(n, "content"))
+	if content == "" {
+		return description
+	}
+
+	// OpenGraph uses property, Twitter uses name, and both are seen in the wild
+	for _, key := range []string{attr(n, "property"), attr(n, "name")} {
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch {
+		case strings.HasPrefix(key, "twitter:"):
+			if _, ok := m.Twitter[key]; !ok {
+				m.Twitter[key] = content
+			}
+		case isOpenGraph(key):
+			if _, ok := m.OpenGraph[key]; !ok {
+				m.OpenGraph[key] = content
+			}
+		case key == "description" && description == "":
+			description = content
+		}
+	}
+
+	if strings.EqualFold(attr(n, "http-equiv"), "content-language") && m.Language == "" {
+		m.Language = content
+	}
+	return description
+}
+
+// isOpenGraph reports whether a meta property belongs to the OpenGraph protocol
+func isOpenGraph(property string) bool {
+	for _, prefix := range openGraphPrefixes {
+		if strings.HasPrefix(property, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addLink records the canonical URL and favicon link tags
+func (m *Metadata) addLink(n *html.Node, base *url.URL) {
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" {
+		return
+	}
+
+	rel := attr(n, "rel")
+	switch {
+	case hasToken(rel, "canonical") && m.CanonicalURL == "":
+		m.CanonicalURL = resolveURL(base, href)
+	case hasToken(rel, "icon") && m.Favicon == "":
+		m.Favicon = resolveURL(base, href)
+	case hasToken(rel, "apple-touch-icon") && m.Favicon == "":
+		m.Favicon = resolveURL(base, href)
+	}
+}
+
+// addJSONLD decodes a JSON-LD script block, skipping it if it is invalid
+func (m *Metadata) addJSONLD(text string) {
+	var value any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &value); err != nil {
+		return
+	}
+	if values, ok := value.([]any); ok {
+		m.JSONLD = append(m.JSONLD, values...)
+		return
+	}
+	m.JSONLD = append(m.JSONLD, value)
+}
+
+// microdataItem collects the properties of an itemscope element
+func microdataItem(n *html.Node, base *url.URL) *MicrodataItem {
+	item := &MicrodataItem{
+		Type:       strings.Fields(attr(n, "itemtype")),
+		ID:         attr(n, "itemid"),
+		Properties: make(map[string][]any),
+	}
+
+	var walk func(c *html.Node)
+	walk = func(c *html.Node) {
+		for ; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			names := strings.Fields(attr(c, "itemprop"))
+			if len(names) > 0 {
+				var value any
+				if hasAttr(c, "itemscope") {
+					value = microdataItem(c, base)
+				} else {
+					value = microdataValue(c, base)
+				}
+				for _, name := range names {
+					item.Properties[name] = append(item.Properties[name], value)
+				}
+			}
+			// A nested item owns the properties below it
+			if !hasAttr(c, "itemscope") {
+				walk(c.FirstChild)
+			}
+		}
+	}
+	walk(n.FirstChild)
+
+	return item
+}
+
+// microdataValue returns the value of an itemprop element that is not an item itself
+func microdataValue(n *html.Node, base *url.URL) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		return strings.TrimSpace(attr(n, "content"))
+	case atom.A, atom.Area, atom.Link:
+		return resolveURL(base, attr(n, "href"))
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe, atom.Embed, atom.Track:
+		return resolveURL(base, attr(n, "src"))
+	case atom.Object:
+		return resolveURL(base, attr(n, "data"))
+	case atom.Time:
+		if datetime := attr(n, "datetime"); datetime != "" {
+			return strings.TrimSpace(datetime)
+		}
+	case atom.Data, atom.Meter:
+		return strings.TrimSpace(attr(n, "value"))
+	}
+	if content := attr(n, "content"); content != "" {
+		return strings.TrimSpace(content)
+	}
+	return collapseSpace(nodeText(n))
+}
+
+// Header renders the metadata as a YAML-style front matter block, or returns
+// an empty string if there is nothing to show
+func (m *Metadata) Header() string {
+	if m == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", name, collapseSpace(value))
+		}
+	}
+	field("title", m.Title)
+	field("description", m.Description)
+	field("canonical_url", m.CanonicalURL)
+	field("language", m.Language)
+	field("site_name", m.SiteName)
+	field("type", m.Type)
+	field("image", m.Image)
+	field("favicon", m.Favicon)
+	field("json_ld", strings.Join(jsonLDTypes(m.JSONLD), ", "))
+	var itemTypes []string
+	for _, item := range m.Microdata {
+		itemTypes = append(itemTypes, item.Type...)
+	}
+	field("microdata", strings.Join(itemTypes, ", "))
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return "---\n" + b.String() + "---\n\n"
+}
+
+// jsonLDTypes lists the distinct @type values of JSON-LD objects, including
+// those in an @graph, in order of appearance
+func jsonLDTypes(values []any) []string {
+	var types []string
+	seen := make(map[string]bool)
+	var add func(value any)
+	add = func(value any) {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		var names []string
+		switch t := object["@type"].(type) {
+		case string:
+			names = []string{t}
+		case []any:
+			for _, name := range t {
+				if s, ok := name.(string); ok {
+					names = append(names, s)
+				}
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				types = append(types, name)
+			}
+		}
+		if graph, ok := object["@graph"].([]any); ok {
+			for _, node := range graph {
+				add(node)
+			}
+		}
+	}
+	for _, value := range values {
+		add(value)
+	}
+	return types
+}
+
+// resolveURL resolves a possibly relative reference against base, returning it
+// unchanged when there is no base or it does not parse
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || base == nil {
+		return ref
+	}
+	resolved, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return resolved.String()
+}
+
+// attr returns the value of an element's attribute, or an empty string
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether an element has an attribute, whatever its value
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText returns the concatenated text of a node and its descendants
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
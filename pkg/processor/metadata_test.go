@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const metadataPage = `<!DOCTYPE html>
+<html lang="en-GB">
+<head>
+  <title>Plain title</title>
+  <meta name="description" content="Plain description">
+  <meta property="og:title" content="Pancakes">
+  <meta property="og:type" content="article">
+  <meta property="og:site_name" content="Cookbook">
+  <meta property="og:image" content="/img/pancakes.jpg">
+  <meta property="article:author" content="Ada">
+  <meta name="twitter:card" content="summary_large_image">
+  <meta name="twitter:description" content="Fluffy pancakes">
+  <link rel="canonical" href="/recipes/pancakes">
+  <link rel="shortcut icon" href="/favicon.png">
+  <script type="application/ld+json">{"@context": "https://schema.org", "@type": "Recipe", "name": "Pancakes"}</script>
+  <script type="application/ld+json">[{"@type": "BreadcrumbList"}, {"@type": ["Thing", "Article"]}]</script>
+  <script type="application/ld+json">{not json</script>
+</head>
+<body>
+  <div itemscope itemtype="https://schema.org/Product">
+    <span itemprop="name">Pancake mix</span>
+    <img itemprop="image" src="mix.jpg">
+    <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+      <meta itemprop="price" content="4.99">
+      <span itemprop="priceCurrency">EUR</span>
+    </div>
+  </div>
+  <article><h1>Pancakes</h1><p>Mix the flour, eggs and milk, then fry in a hot pan until golden.</p></article>
+</body>
+</html>`
+
+func TestExtractMetadata(t *testing.T) {
+	m := ExtractMetadata([]byte(metadataPage), "https://example.com/recipes/pancakes?ref=home")
+
+	checks := map[string][2]string{
+		"title":          {m.Title, "Pancakes"},
+		"description":    {m.Description, "Fluffy pancakes"},
+		"canonical_url":  {m.CanonicalURL, "https://example.com/recipes/pancakes"},
+		"language":       {m.Language, "en-GB"},
+		"favicon":        {m.Favicon, "https://example.com/favicon.png"},
+		"site_name":      {m.SiteName, "Cookbook"},
+		"type":           {m.Type, "article"},
+		"image":          {m.Image, "https://example.com/img/pancakes.jpg"},
+		"article:author": {m.OpenGraph["article:author"], "Ada"},
+		"twitter:card":   {m.Twitter["twitter:card"], "summary_large_image"},
+	}
+	for name, check := range checks {
+		if check[0] != check[1] {
+			t.Errorf("expected %s %q, got %q", name, check[1], check[0])
+		}
+	}
+
+	if len(m.JSONLD) != 3 {
+		t.Fatalf("expected 3 JSON-LD objects, got %d: %v", len(m.JSONLD), m.JSONLD)
+	}
+	if recipe, ok := m.JSONLD[0].(map[string]any); !ok || recipe["name"] != "Pancakes" {
+		t.Errorf("expected the recipe object first, got %v", m.JSONLD[0])
+	}
+
+	if len(m.Microdata) != 1 {
+		t.Fatalf("expected 1 top-level microdata item, got %d", len(m.Microdata))
+	}
+	product := m.Microdata[0]
+	if product.Type[0] != "https://schema.org/Product" || product.Properties["name"][0] != "Pancake mix" ||
+		product.Properties["image"][0] != "https://example.com/recipes/mix.jpg" {
+		t.Errorf("unexpected product item: %+v", product)
+	}
+	offer, ok := product.Properties["offers"][0].(*MicrodataItem)
+	if !ok || offer.Properties["price"][0] != "4.99" || offer.Properties["priceCurrency"][0] != "EUR" {
+		t.Errorf("unexpected offer item: %+v", product.Properties["offers"])
+	}
+	if _, ok := product.Properties["price"]; ok {
+		t.Error("expected the offer's properties to stay on the nested item")
+	}
+}
+
+func TestExtractMetadataFallbacks(t *testing.T) {
+	m := ExtractMetadata([]byte(`<html><head><base href="https://cdn.example.org/">
+<title> Only a title </title><meta name="description" content="Described">
+<meta property="og:locale" content="fr_FR"><link rel="icon" href="icon.ico"></head></html>`), "https://example.com/page")
+
+	if m.Title != "Only a title" || m.Description != "Described" || m.Language != "fr-FR" {
+		t.Errorf("unexpected fallbacks: %+v", m)
+	}
+	if m.Favicon != "https://cdn.example.org/icon.ico" {
+		t.Errorf("expected the favicon resolved against <base>, got %q", m.Favicon)
+	}
+	if m.OpenGraph["og:locale"] != "fr_FR" || m.Twitter != nil || m.JSONLD != nil || m.Microdata != nil {
+		t.Errorf("unexpected collections: %+v", m)
+	}
+}
+
+func TestMetadataHeader(t *testing.T) {
+	processor := NewContentProcessor()
+
+	in := &Input{Body: []byte(metadataPage), MediaType: "text/html", URL: "https://example.com/recipes/pancakes"}
+	doc, err := processor.Process(context.Background(), in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Metadata == nil || doc.Metadata.SiteName != "Cookbook" {
+		t.Errorf("expected metadata on the document, got %+v", doc.Metadata)
+	}
+	if strings.HasPrefix(doc.Content, "---") {
+		t.Errorf("expected no header unless asked for, got %q", doc.Content)
+	}
+
+	in.MetadataHeader = true
+	doc, err = processor.Process(context.Background(), in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "---\ntitle: Pancakes\ndescription: Fluffy pancakes\ncanonical_url: https://example.com/recipes/pancakes\n" +
+		"language: en-GB\nsite_name: Cookbook\ntype: article\nimage: https://example.com/img/pancakes.jpg\n" +
+		"favicon: https://example.com/favicon.png\njson_ld: Recipe, BreadcrumbList, Article, Thing\n" +
+		"microdata: https://schema.org/Product\n---\n\n"
+	if !strings.HasPrefix(doc.Content, expected) {
+		t.Errorf("expected header:\n%s\ngot:\n%s", expected, doc.Content)
+	}
+	if !strings.Contains(doc.Content, "Mix the flour") {
+		t.Errorf("expected the article after the header, got %q", doc.Content)
+	}
+
+	if header := (&Metadata{}).Header(); header != "" {
+		t.Errorf("expected no header for empty metadata, got %q", header)
+	}
+}
@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// sectionPathSeparator separates the headings of a section path such as "Configuration > Proxy"
+const sectionPathSeparator = ">"
+
+// markdownLink matches inline links and images, capturing their text
+var markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+
+// Heading is a markdown heading of processed content
+type Heading struct {
+	// Level is the heading depth, from 1 to 6
+	Level int `json:"level"`
+	// Text is the heading text without markdown syntax
+	Text string `json:"text"`
+	// Anchor is the GitHub-style slug of the text, unique within the content
+	Anchor string `json:"anchor"`
+	// Offset is where the heading starts, usable as a start index
+	Offset int `json:"offset"`
+	// Length is the size of the section the heading opens, up to the next
+	// heading of the same or a higher level
+	Length int `json:"length"`
+}
+
+// heading is a heading located by byte offsets
+type heading struct {
+	level        int
+	text, anchor string
+	start, end   int
+	// parent is the index of the enclosing heading, or -1
+	parent int
+}
+
+// Outline returns the headings of markdown content in document order, with
+// offsets and lengths measured in unit. Headings inside fenced code blocks are
+// ignored.
+func Outline(content string, unit Unit) []Heading {
+	offsets := newUnitOffsets(content, unit)
+	found := parseHeadings(content)
+
+	outline := make([]Heading, 0, len(found))
+	for _, h := range found {
+		start := offsets.unitIndex(h.start)
+		outline = append(outline, Heading{
+			Level:  h.level,
+			Text:   h.text,
+			Anchor: h.anchor,
+			Offset: start,
+			Length: offsets.unitIndex(h.end) - start,
+		})
+	}
+	return outline
+}
+
+// FormatOutline renders an outline as a nested markdown list showing the
+// anchor and offset of each heading
+func FormatOutline(outline []Heading, unit Unit) string {
+	if len(outline) == 0 {
+		return "No headings found."
+	}
+
+	top := outline[0].Level
+	for _, h := range outline {
+		top = min(top, h.Level)
+	}
+
+	if unit == "" {
+		unit = UnitChars
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Outline of %d headings, with offsets in %s usable as start_index:\n", len(outline), unit)
+	for _, h := range outline {
+		indent := strings.Repeat("  ", h.Level-top)
+		fmt.Fprintf(&b, "\n%s- %s (#%s) · offset %d · length %d", indent, h.Text, h.Anchor, h.Offset, h.Length)
+	}
+	return b.String()
+}
+
+// Section returns the part of markdown content under a heading, from the
+// heading line up to the next heading of the same or a higher level. The query
+// is either an anchor, with or without its leading "#", or a path of heading
+// texts such as "Configuration > Proxy" whose parts must name the heading and,
+// in order, some of its ancestors. Texts compare case-insensitively and the
+// first match in document order wins.
+func Section(content, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("section must not be empty")
+	}
+
+	found := parseHeadings(content)
+	if anchor, ok := strings.CutPrefix(query, "#"); ok {
+		for _, h := range found {
+			if h.anchor == anchor {
+				return strings.TrimRight(content[h.start:h.end], "\n"), nil
+			}
+		}
+		return "", fmt.Errorf("section %q not found", query)
+	}
+
+	path := strings.Split(query, sectionPathSeparator)
+	for i := range path {
+		path[i] = strings.TrimSpace(path[i])
+	}
+	for i, h := range found {
+		if matchesPath(found, i, path) || (len(path) == 1 && h.anchor == query) {
+			return strings.TrimRight(content[h.start:h.end], "\n"), nil
+		}
+	}
+	return "", fmt.Errorf("section %q not found", query)
+}
+
+// matchesPath reports whether the heading at index i has the last text of path
+// and the other texts among its ancestors, in order
+func matchesPath(found []heading, i int, path []string) bool {
+	if !strings.EqualFold(found[i].text, path[len(path)-1]) {
+		return false
+	}
+	rest := path[:len(path)-1]
+	for p := found[i].parent; p >= 0 && len(rest) > 0; p = found[p].parent {
+		if strings.EqualFold(found[p].text, rest[len(rest)-1]) {
+			rest = rest[:len(rest)-1]
+		}
+	}
+	return len(rest) == 0
+}
+
+// parseHeadings finds the ATX headings of markdown content outside fenced code blocks
+func parseHeadings(content string) []heading {
+	blocks := codeBlocks(content, len(content))
+	slugs := make(map[string]int)
+
+	var found []heading
+	for pos := 0; pos < len(content); {
+		line, next := lineAt(content, pos)
+		if codeBlockAt(blocks, pos) == nil && isHeading(line) {
+			level, text := headingText(line)
+			h := heading{level: level, text: text, anchor: uniqueSlug(slugs, text), start: pos, end: len(content), parent: -1}
+
+			// Close the sections this heading ends and find its parent
+			for i := len(found) - 1; i >= 0; i-- {
+				if found[i].level < level {
+					h.parent = i
+					break
+				}
+				if found[i].end == len(content) {
+					found[i].end = pos
+				}
+			}
+			found = append(found, h)
+		}
+		pos = next
+	}
+	return found
+}
+
+// headingText returns the level and plain text of an ATX heading line
+func headingText(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	text := strings.TrimLeft(trimmed, "#")
+	level := len(trimmed) - len(text)
+
+	// Drop the optional closing sequence of hashes
+	text = strings.TrimSpace(text)
+	if stripped := strings.TrimRight(text, "#"); stripped == "" || strings.HasSuffix(stripped, " ") {
+		text = strings.TrimSpace(stripped)
+	}
+
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+	return level, collapseSpace(text)
+}
+
+// uniqueSlug returns the GitHub-style anchor of a heading text, suffixed with
+// a counter when an earlier heading has the same anchor
+func uniqueSlug(slugs map[string]int, text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	slug := b.String()
+
+	n := slugs[slug]
+	slugs[slug] = n + 1
+	if n > 0 {
+		return fmt.Sprintf("%s-%d", slug, n)
+	}
+	return slug
+}
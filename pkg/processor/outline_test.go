@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+const outlineDoc = `# Guide
+
+Intro.
+
+## Installation
+
+Run the installer.
+
+## Configuration ##
+
+Settings live in config.yaml.
+
+### Proxy
+
+Set proxy_url.
+
+` + "```sh\n# not a heading\n```" + `
+
+### [Timeouts](#timeouts)
+
+Defaults to 30s.
+
+## Proxy
+
+Unrelated to configuration.
+
+# Appendix
+`
+
+func TestOutline(t *testing.T) {
+	outline := Outline(outlineDoc, UnitChars)
+
+	expected := []struct {
+		level  int
+		text   string
+		anchor string
+	}{
+		{1, "Guide", "guide"},
+		{2, "Installation", "installation"},
+		{2, "Configuration", "configuration"},
+		{3, "Proxy", "proxy"},
+		{3, "Timeouts", "timeouts"},
+		{2, "Proxy", "proxy-1"},
+		{1, "Appendix", "appendix"},
+	}
+	if len(outline) != len(expected) {
+		t.Fatalf("expected %d headings, got %d: %+v", len(expected), len(outline), outline)
+	}
+	for i, want := range expected {
+		got := outline[i]
+		if got.Level != want.level || got.Text != want.text || got.Anchor != want.anchor {
+			t.Errorf("heading %d: expected %+v, got %+v", i, want, got)
+		}
+		if !strings.HasPrefix(outlineDoc[got.Offset:], strings.Repeat("#", got.Level)+" ") {
+			t.Errorf("heading %d: offset %d does not point at the heading", i, got.Offset)
+		}
+	}
+
+	config := outline[2]
+	if section := outlineDoc[config.Offset : config.Offset+config.Length]; !strings.HasSuffix(section, "Defaults to 30s.\n\n") {
+		t.Errorf("expected the configuration section to end before the next level 2 heading, got %q", section)
+	}
+	if last := outline[6]; last.Offset+last.Length != len(outlineDoc) {
+		t.Errorf("expected the last section to run to the end, got %+v", last)
+	}
+}
+
+func TestOutlineUnits(t *testing.T) {
+	content := "# Café\n\nÉté.\n\n## Über\n"
+	chars := Outline(content, UnitChars)
+	bytes := Outline(content, UnitBytes)
+	if chars[1].Offset != 14 || bytes[1].Offset != 17 {
+		t.Errorf("expected offsets 14 chars and 17 bytes, got %d and %d", chars[1].Offset, bytes[1].Offset)
+	}
+	if chars[1].Anchor != "über" {
+		t.Errorf("expected a unicode anchor, got %q", chars[1].Anchor)
+	}
+}
+
+func TestFormatOutline(t *testing.T) {
+	formatted := FormatOutline(Outline(outlineDoc, UnitChars), "")
+	if !strings.HasPrefix(formatted, "Outline of 7 headings, with offsets in chars") {
+		t.Errorf("unexpected header: %q", formatted)
+	}
+	if !strings.Contains(formatted, "\n    - Proxy (#proxy) · offset ") {
+		t.Errorf("expected level 3 headings indented twice: %q", formatted)
+	}
+	if FormatOutline(nil, UnitChars) != "No headings found." {
+		t.Error("expected a note for content without headings")
+	}
+}
+
+func TestSection(t *testing.T) {
+	tests := []struct {
+		query string
+		start string
+		end   string
+	}{
+		{"Configuration > Proxy", "### Proxy", "# not a heading\n```"},
+		{"guide > proxy", "### Proxy", "# not a heading\n```"},
+		{"Proxy", "### Proxy", "# not a heading\n```"},
+		{"#proxy-1", "## Proxy", "Unrelated to configuration."},
+		{"timeouts", "### [Timeouts](#timeouts)", "Defaults to 30s."},
+		{"Configuration", "## Configuration ##", "Defaults to 30s."},
+		{"Appendix", "# Appendix", "# Appendix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			section, err := Section(outlineDoc, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(section, tt.start) || !strings.HasSuffix(section, tt.end) {
+				t.Errorf("expected a section from %q to %q, got %q", tt.start, tt.end, section)
+			}
+		})
+	}
+
+	for _, query := range []string{"Installation > Proxy", "#missing", "Nothing", " "} {
+		if _, err := Section(outlineDoc, query); err == nil {
+			t.Errorf("expected an error for %q", query)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package processor
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Line reconstruction thresholds, as fractions of the font size
+const (
+	// pdfBaselineTolerance is how far glyphs may sit from a line's baseline and still belong to it
+	pdfBaselineTolerance = 0.5
+	// pdfWordGap is the horizontal gap between glyphs that is read as a space
+	pdfWordGap = 0.2
+	// pdfParagraphGap is the vertical gap between lines that is read as a paragraph break
+	pdfParagraphGap = 1.8
+)
+
+// PageRange is an inclusive range of 1-based page numbers. A Last of zero means
+// the range runs to the end of the document.
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// contains reports whether page falls inside the range
+func (r PageRange) contains(page int) bool {
+	return page >= r.First && (r.Last == 0 || page <= r.Last)
+}
+
+// ParsePageRanges parses a comma-separated list of page numbers and ranges such
+// as "1-3,5,8-". An empty spec selects every page and returns nil.
+func ParsePageRanges(spec string) ([]PageRange, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var ranges []PageRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+
+		r := PageRange{}
+		var err error
+		if r.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil || r.First < 1 {
+			return nil, fmt.Errorf("invalid page range %q: pages are numbered from 1", part)
+		}
+
+		switch {
+		case !isRange:
+			r.Last = r.First
+		case strings.TrimSpace(last) != "":
+			if r.Last, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || r.Last < r.First {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+// handlePDF extracts the text of the selected pages of a PDF document, each
+// preceded by a page marker, along with the document's title and author
+func handlePDF(ctx context.Context, in *Input) (doc *Document, err error) {
+	// The PDF reader panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(in.Body), int64(len(in.Body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	doc = &Document{
+		Title:     strings.TrimSpace(info.Key("Title").Text()),
+		Byline:    strings.TrimSpace(info.Key("Author").Text()),
+		Published: parsePDFDate(info.Key("CreationDate").Text()),
+		PageCount: reader.NumPage(),
+	}
+
+	var text strings.Builder
+	for num := 1; num <= doc.PageCount; num++ {
+		if !pageSelected(in.Pages, num) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&text, "--- Page %d of %d ---\n\n", num, doc.PageCount)
+		if pageText := pdfPageText(reader.Page(num)); pageText != "" {
+			text.WriteString(pageText)
+			text.WriteString("\n\n")
+		}
+	}
+
+	doc.Content = strings.TrimRight(text.String(), "\n")
+	return doc, nil
+}
+
+// pageSelected reports whether a page is part of the selected ranges; no ranges select every page
+func pageSelected(ranges []PageRange, page int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if r.contains(page) {
+			return true
+		}
+	}
+	return false
+}
+
+// pdfPageText reassembles a page's glyphs into lines in reading order,
+// inserting spaces at word gaps and blank lines at paragraph gaps.
+// Unreadable pages yield no text.
+func pdfPageText(page pdf.Page) (text string) {
+	defer func() {
+		if recover() != nil {
+			text = ""
+		}
+	}()
+
+	if page.V.IsNull() {
+		return ""
+	}
+
+	return strings.TrimSpace(pdfLinesText(pdfLines(page.Content().Text)))
+}
+
+// pdfLine is a run of glyphs sharing a baseline
+type pdfLine struct {
+	y      float64
+	size   float64
+	glyphs []pdf.Text
+}
+
+// pdfLines groups glyphs into lines by baseline, whatever order they are drawn
+// in. Lines are ordered top to bottom and their glyphs left to right.
+func pdfLines(glyphs []pdf.Text) []*pdfLine {
+	var lines []*pdfLine
+	for _, glyph := range glyphs {
+		i := slices.IndexFunc(lines, func(line *pdfLine) bool {
+			return math.Abs(line.y-glyph.Y) <= line.size*pdfBaselineTolerance
+		})
+		if i < 0 {
+			lines = append(lines, &pdfLine{y: glyph.Y, size: math.Max(glyph.FontSize, 1)})
+			i = len(lines) - 1
+		}
+		lines[i].glyphs = append(lines[i].glyphs, glyph)
+	}
+
+	// PDF coordinates grow upwards
+	slices.SortStableFunc(lines, func(a, b *pdfLine) int {
+		return cmp.Compare(b.y, a.y)
+	})
+	for _, line := range lines {
+		slices.SortStableFunc(line.glyphs, func(a, b pdf.Text) int {
+			return cmp.Compare(a.X, b.X)
+		})
+	}
+	return lines
+}
+
+// pdfLinesText joins lines of glyphs into text, separating lines further apart
+// than the paragraph gap with a blank line
+func pdfLinesText(lines []*pdfLine) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			if lines[i-1].y-line.y > lines[i-1].size*pdfParagraphGap {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString("\n")
+			}
+		}
+
+		for j, glyph := range line.glyphs {
+			if j > 0 {
+				prev := line.glyphs[j-1]
+				if glyph.X-(prev.X+prev.W) > math.Max(prev.FontSize, 1)*pdfWordGap && !strings.HasSuffix(b.String(), " ") {
+					b.WriteString(" ")
+				}
+			}
+			b.WriteString(glyph.S)
+		}
+	}
+	return b.String()
+}
+
+// parsePDFDate parses a PDF date string such as "D:20240301100000+01'00'",
+// returning nil if it is missing or malformed
+func parsePDFDate(value string) *time.Time {
+	digits := strings.TrimPrefix(strings.TrimSpace(value), "D:")
+	zone := ""
+	if i := strings.IndexAny(digits, "Z+-"); i >= 0 {
+		digits, zone = digits[:i], strings.ReplaceAll(digits[i:], "'", "")
+	}
+
+	// Missing trailing fields default to their lowest value
+	const defaults = "00000101000000"
+	if len(digits) < 4 || len(digits) > len(defaults) {
+		return nil
+	}
+	digits += defaults[len(digits):]
+
+	layout := "20060102150405"
+	switch len(zone) {
+	case 0, 1:
+		// No offset, or Z for UTC
+	case 3:
+		layout, digits = layout+"-0700", digits+zone+"00"
+	case 5:
+		layout, digits = layout+"-0700", digits+zone
+	default:
+		return nil
+	}
+
+	t, err := time.Parse(layout, digits)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
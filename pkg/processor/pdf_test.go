@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// buildPDF writes a minimal PDF document with one page per entry of pages, each
+// page holding one line of text per entry
+func buildPDF(title, author string, pages [][]string) []byte {
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		fmt.Sprintf("<< /Title (%s) /Author (%s) /CreationDate (D:20240301100000Z) >>", title, author),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	for _, lines := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 72 720 Td")
+		for _, line := range lines {
+			fmt.Fprintf(&content, " (%s) Tj 0 -14 Td", line)
+		}
+		content.WriteString(" ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents %d 0 R >>",
+				len(objects)+2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func TestHandlePDF(t *testing.T) {
+	body := buildPDF("A Paper", "Ada Lovelace", [][]string{
+		{"Abstract", "First page text."},
+		{"Second page text."},
+		{"Third page text."},
+	})
+
+	processor := NewContentProcessor()
+	doc, err := processor.Process(context.Background(), &Input{Body: body, MediaType: "application/pdf"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if doc.Title != "A Paper" || doc.Byline != "Ada Lovelace" || doc.PageCount != 3 {
+		t.Errorf("unexpected metadata: title %q, author %q, %d pages", doc.Title, doc.Byline, doc.PageCount)
+	}
+	if doc.Published == nil || !doc.Published.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected creation date 2024-03-01T10:00:00Z, got %v", doc.Published)
+	}
+
+	expected := "--- Page 1 of 3 ---\n\nAbstract\nFirst page text.\n\n" +
+		"--- Page 2 of 3 ---\n\nSecond page text.\n\n" +
+		"--- Page 3 of 3 ---\n\nThird page text."
+	if doc.Content != expected {
+		t.Errorf("expected %q, got %q", expected, doc.Content)
+	}
+
+	ranges, _ := ParsePageRanges("2-")
+	doc, err = processor.Process(context.Background(), &Input{Body: body, MediaType: "application/pdf", Pages: ranges})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(doc.Content, "Page 1 of 3") || !strings.HasPrefix(doc.Content, "--- Page 2 of 3 ---") {
+		t.Errorf("expected only pages 2 and 3, got %q", doc.Content)
+	}
+}
+
+func TestHandlePDFMalformed(t *testing.T) {
+	_, err := handlePDF(context.Background(), &Input{Body: []byte("%PDF-1.4\nnot really a pdf"), MediaType: "application/pdf"})
+	if err == nil {
+		t.Error("expected an error for a malformed PDF")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = handlePDF(ctx, &Input{Body: buildPDF("T", "A", [][]string{{"text"}}), MediaType: "application/pdf"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPDFLinesReadingOrder(t *testing.T) {
+	// A two-column page drawn column by column, bottom line first, with the
+	// words of its first line out of order
+	glyphs := []pdf.Text{
+		{S: "B2", X: 300, Y: 706, W: 12, FontSize: 12},
+		{S: "B1", X: 300, Y: 720, W: 12, FontSize: 12},
+		{S: "A2", X: 72, Y: 706.5, W: 12, FontSize: 12},
+		{S: "world", X: 100, Y: 720, W: 30, FontSize: 12},
+		{S: "Hello", X: 72, Y: 720, W: 25, FontSize: 12},
+		{S: "End", X: 72, Y: 650, W: 20, FontSize: 12},
+	}
+
+	expected := "Hello world B1\nA2 B2\n\nEnd"
+	if text := pdfLinesText(pdfLines(glyphs)); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestParsePageRanges(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []PageRange
+		wantErr  bool
+	}{
+		{spec: "", expected: nil},
+		{spec: "3", expected: []PageRange{{3, 3}}},
+		{spec: "1-3, 5, 8-", expected: []PageRange{{1, 3}, {5, 5}, {8, 0}}},
+		{spec: "0", wantErr: true},
+		{spec: "4-2", wantErr: true},
+		{spec: "a-b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ranges, err := ParsePageRanges(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePageRanges(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if fmt.Sprint(ranges) != fmt.Sprint(tt.expected) {
+			t.Errorf("ParsePageRanges(%q) = %v, expected %v", tt.spec, ranges, tt.expected)
+		}
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"D:20240301100000Z", "2024-03-01T10:00:00Z"},
+		{"D:20240301100000+01'00'", "2024-03-01T10:00:00+01:00"},
+		{"D:2024", "2024-01-01T00:00:00Z"},
+		{"", ""},
+		{"garbage", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if date := parsePDFDate(tt.value); date != nil {
+			got = date.Format(time.RFC3339)
+		}
+		if got != tt.expected {
+			t.Errorf("parsePDFDate(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
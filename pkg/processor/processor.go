@@ -2,7 +2,14 @@
 package processor
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/go-shiori/go-readability"
@@ -12,58 +19,322 @@ import (
 // ContentProcessor handles HTML processing and content formatting
 type ContentProcessor struct {
 	htmlConverter *md.Converter
+
+	mu       sync.RWMutex
+	handlers map[string]ContentHandler
 }
 
-// NewContentProcessor creates a new content processor instance
+// NewContentProcessor creates a new content processor instance with handlers
+// for HTML, plain text, JSON, XML, feeds and PDF registered
 func NewContentProcessor() *ContentProcessor {
 	converter := md.NewConverter("", true, nil)
-	return &ContentProcessor{
+	p := &ContentProcessor{
 		htmlConverter: converter,
+		handlers:      make(map[string]ContentHandler),
 	}
+	p.registerBuiltinHandlers()
+	return p
 }
 
 // ProcessHTML converts HTML content to readable markdown
 func (p *ContentProcessor) ProcessHTML(htmlContent string) string {
+	markdown, _ := p.ProcessHTMLContext(context.Background(), htmlContent)
+	return markdown
+}
+
+// ProcessHTMLContext is like ProcessHTML but stops between processing stages
+// once the context is done, returning the context's error
+func (p *ContentProcessor) ProcessHTMLContext(ctx context.Context, htmlContent string) (string, error) {
+	doc, err := p.ProcessHTMLDocument(ctx, htmlContent)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// Document is content converted to text, along with the metadata found in it
+type Document struct {
+	Content string
+	Title   string
+	// Byline is the author of the article or document
+	Byline    string
+	Published *time.Time
+	// PageCount is the number of pages of paged formats such as PDF, zero otherwise
+	PageCount int
+	// Metadata is the page metadata of HTML documents, nil for other formats
+	Metadata *Metadata
+}
+
+// ProcessHTMLDocument is like ProcessHTMLContext but also returns the article's
+// and the page's metadata
+func (p *ContentProcessor) ProcessHTMLDocument(ctx context.Context, htmlContent string) (*Document, error) {
+	return p.processHTML(ctx, htmlContent, htmlOptions{})
+}
+
+// htmlOptions controls the conversion of an HTML page to markdown
+type htmlOptions struct {
+	// pageURL is the page's address, which the URLs in its metadata resolve against
+	pageURL string
+	// absoluteURLs rewrites relative link and image URLs against pageURL and the page's <base>
+	absoluteURLs bool
+	// selection picks the elements to convert instead of readability's article
+	selection *Selection
+}
+
+// processHTML converts HTML to markdown
+func (p *ContentProcessor) processHTML(ctx context.Context, htmlContent string, opts htmlOptions) (*Document, error) {
 	// Parse HTML document
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
-		return htmlContent
+		return &Document{Content: htmlContent}, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Collect the head's metadata, which readability leaves out
+	result := &Document{Metadata: extractMetadata(doc, opts.pageURL)}
+
+	// Rewrite relative URLs on the whole page, so they are absolute whether or
+	// not readability finds an article
+	if base := documentBase(doc, opts.pageURL); opts.absoluteURLs && base != nil {
+		absolutizeURLs(doc, base)
+		var rendered strings.Builder
+		if err := html.Render(&rendered, doc); err == nil {
+			htmlContent = rendered.String()
+		}
+	}
+
+	// Convert only the selected elements, bypassing readability
+	if opts.selection != nil {
+		markdown, err := p.convertSelection(doc, opts.selection)
+		if err != nil {
+			return nil, err
+		}
+		result.Title = result.Metadata.Title
+		result.Content = markdown
+		return result, nil
 	}
 
 	// Extract readable content using readability
 	article, err := readability.FromDocument(doc, nil)
-	if err == nil && article.Content != "" {
-		htmlContent = article.Content
+	if err == nil {
+		result.Title = article.Title
+		result.Byline = article.Byline
+		result.Published = article.PublishedTime
+		if article.Content != "" {
+			htmlContent = article.Content
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Convert to markdown
 	markdown, err := p.htmlConverter.ConvertString(htmlContent)
 	if err != nil {
-		return htmlContent
+		result.Content = htmlContent
+		return result, nil
 	}
 
-	return markdown
+	result.Content = markdown
+	return result, nil
+}
+
+// Unit is the unit in which pagination offsets and lengths are measured
+type Unit string
+
+// Pagination units
+const (
+	// UnitChars counts Unicode characters (runes)
+	UnitChars Unit = "chars"
+	// UnitBytes counts UTF-8 bytes; windows never split a character
+	UnitBytes Unit = "bytes"
+	// UnitTokens counts estimated LLM tokens
+	UnitTokens Unit = "tokens"
+)
+
+// ParseUnit validates a unit name, defaulting to UnitChars when empty
+func ParseUnit(name string) (Unit, error) {
+	switch unit := Unit(strings.ToLower(name)); unit {
+	case "":
+		return UnitChars, nil
+	case UnitChars, UnitBytes, UnitTokens:
+		return unit, nil
+	default:
+		return "", fmt.Errorf("unsupported unit %q: must be chars, bytes or tokens", name)
+	}
+}
+
+// PageOptions selects a window of content
+type PageOptions struct {
+	StartIndex *int
+	MaxLength  *int
+	Unit       Unit
+	// BoundaryAware backs a truncated window off to the nearest paragraph,
+	// heading, list-item or line boundary, never splitting tables or words,
+	// and closes any code fence left open
+	BoundaryAware bool
 }
 
-// FormatContent applies pagination and truncation to content
-func (*ContentProcessor) FormatContent(content string, startIndex, maxLength *int) string {
+// Page is a window of content. Offsets and lengths are measured in the page's unit.
+type Page struct {
+	// Content is the selected window, followed by a footer when more content remains
+	Content   string
+	Unit      Unit
+	Start     int
+	End       int
+	Total     int
+	Truncated bool
+}
+
+// FormatContent applies pagination and truncation to content, measured in characters
+func (p *ContentProcessor) FormatContent(content string, startIndex, maxLength *int) string {
+	return p.Paginate(content, PageOptions{StartIndex: startIndex, MaxLength: maxLength}).Content
+}
+
+// Paginate selects the window of content described by the options. Windows are
+// always cut on character boundaries, so the result is valid UTF-8. When more
+// content remains, the footer names the start index of the next page.
+func (*ContentProcessor) Paginate(content string, opts PageOptions) *Page {
+	unit := opts.Unit
+	if unit == "" {
+		unit = UnitChars
+	}
+
+	offsets := newUnitOffsets(content, unit)
+	total := offsets.count()
+
 	// Apply start index offset
 	start := 0
-	if startIndex != nil {
-		start = *startIndex
+	if opts.StartIndex != nil {
+		start = max(*opts.StartIndex, 0)
+	}
+	start = min(start, total)
+
+	// Apply length limit
+	end := total
+	if opts.MaxLength != nil && end-start > *opts.MaxLength {
+		end = start + max(*opts.MaxLength, 0)
+	}
+
+	startByte, endByte := offsets.byteOffset(start), offsets.byteOffset(end)
+
+	// Return at least one whole character, even if it is longer than the
+	// limit, so that paging always moves forward. A zero limit asks for an
+	// empty window instead.
+	if start < total && endByte <= startByte && (opts.MaxLength == nil || *opts.MaxLength > 0) {
+		if unit == UnitBytes {
+			_, size := utf8.DecodeRuneInString(content[startByte:])
+			end, endByte = startByte+size, startByte+size
+		} else {
+			end = start + 1
+			endByte = offsets.byteOffset(end)
+		}
 	}
 
-	if start > len(content) {
-		start = len(content)
+	text := content[startByte:endByte]
+	if opts.BoundaryAware {
+		blocks := codeBlocks(content, endByte)
+		if cut := boundaryCut(content, startByte, endByte, blocks); cut != endByte {
+			// Snap to the unit grid, keeping the original end if that would empty the page
+			if index := offsets.unitIndex(cut); index > start {
+				end = index
+				endByte = offsets.byteOffset(end)
+			}
+		}
+		text = fenceWindow(content, startByte, endByte, blocks)
+		if end < total {
+			text = strings.TrimRightFunc(text, unicode.IsSpace)
+		}
 	}
 
-	content = content[start:]
+	if unit == UnitBytes {
+		// Report the character-aligned window that is actually returned
+		start, end = startByte, endByte
+	}
 
-	// Apply length limit
-	if maxLength != nil && len(content) > *maxLength {
-		content = content[:*maxLength]
-		content += "\n\n[Content truncated. Use start_index to get more content.]"
+	page := &Page{
+		Content:   text,
+		Unit:      unit,
+		Start:     start,
+		End:       end,
+		Total:     total,
+		Truncated: end < total,
 	}
 
-	return content
+	if page.Truncated {
+		page.Content += fmt.Sprintf("\n\n[Content truncated. Use start_index=%d to get more content.]", end)
+	}
+
+	return page
+}
+
+// unitOffsets maps unit indexes of a string to byte offsets
+type unitOffsets struct {
+	content string
+	unit    Unit
+	// tokens holds token boundaries, computed only for UnitTokens
+	tokens []int
+}
+
+// newUnitOffsets prepares offset lookups for content measured in unit
+func newUnitOffsets(content string, unit Unit) *unitOffsets {
+	offsets := &unitOffsets{content: content, unit: unit}
+	if unit == UnitTokens {
+		offsets.tokens = tokenBoundaries(content)
+	}
+	return offsets
+}
+
+// count returns the length of the content in the unit
+func (o *unitOffsets) count() int {
+	switch o.unit {
+	case UnitBytes:
+		return len(o.content)
+	case UnitTokens:
+		return len(o.tokens) - 1
+	default:
+		return utf8.RuneCountInString(o.content)
+	}
+}
+
+// unitIndex converts a byte offset on a character boundary to the index of the
+// last unit starting at or before it
+func (o *unitOffsets) unitIndex(offset int) int {
+	switch o.unit {
+	case UnitBytes:
+		return offset
+	case UnitTokens:
+		return sort.SearchInts(o.tokens, offset+1) - 1
+	default:
+		return utf8.RuneCountInString(o.content[:offset])
+	}
+}
+
+// byteOffset converts a unit index, between zero and count, to a byte offset on
+// a character boundary. Byte indexes inside a character move back to its start.
+func (o *unitOffsets) byteOffset(index int) int {
+	switch o.unit {
+	case UnitBytes:
+		for index > 0 && index < len(o.content) && !utf8.RuneStart(o.content[index]) {
+			index--
+		}
+		return index
+	case UnitTokens:
+		return o.tokens[index]
+	default:
+		if index >= len(o.content) {
+			return len(o.content)
+		}
+		for offset := range o.content {
+			if index == 0 {
+				return offset
+			}
+			index--
+		}
+		return len(o.content)
+	}
 }
@@ -1,7 +1,12 @@
 package processor
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestNewContentProcessor(t *testing.T) {
@@ -41,14 +46,14 @@ func TestFormatContent(t *testing.T) {
 			content:    "Hello, World!",
 			startIndex: nil,
 			maxLength:  intPtr(5),
-			expected:   "Hello\n\n[Content truncated. Use start_index to get more content.]",
+			expected:   "Hello\n\n[Content truncated. Use start_index=5 to get more content.]",
 		},
 		{
 			name:       "with start index and max length",
 			content:    "Hello, World!",
 			startIndex: intPtr(7),
 			maxLength:  intPtr(3),
-			expected:   "Wor\n\n[Content truncated. Use start_index to get more content.]",
+			expected:   "Wor\n\n[Content truncated. Use start_index=10 to get more content.]",
 		},
 		{
 			name:       "start index beyond content length",
@@ -57,6 +62,20 @@ func TestFormatContent(t *testing.T) {
 			maxLength:  nil,
 			expected:   "",
 		},
+		{
+			name:       "multi-byte characters are counted as characters",
+			content:    "日本語のテキスト",
+			startIndex: intPtr(2),
+			maxLength:  intPtr(3),
+			expected:   "語のテ\n\n[Content truncated. Use start_index=5 to get more content.]",
+		},
+		{
+			name:       "emoji are not split",
+			content:    "👋🌍!",
+			startIndex: intPtr(1),
+			maxLength:  nil,
+			expected:   "🌍!",
+		},
 		{
 			name:       "max length larger than content",
 			content:    "Hello",
@@ -118,6 +137,187 @@ func TestProcessHTML(t *testing.T) {
 	}
 }
 
+func TestPaginate(t *testing.T) {
+	processor := NewContentProcessor()
+
+	tests := []struct {
+		name          string
+		content       string
+		opts          PageOptions
+		expected      string
+		expectedStart int
+		expectedEnd   int
+		expectedTotal int
+	}{
+		{
+			name:          "chars",
+			content:       "héllo wörld",
+			opts:          PageOptions{StartIndex: intPtr(6), Unit: UnitChars},
+			expected:      "wörld",
+			expectedStart: 6,
+			expectedEnd:   11,
+			expectedTotal: 11,
+		},
+		{
+			name:          "default unit is chars",
+			content:       "héllo",
+			opts:          PageOptions{MaxLength: intPtr(2)},
+			expected:      "hé\n\n[Content truncated. Use start_index=2 to get more content.]",
+			expectedStart: 0,
+			expectedEnd:   2,
+			expectedTotal: 5,
+		},
+		{
+			name:          "bytes",
+			content:       "hello world",
+			opts:          PageOptions{StartIndex: intPtr(6), MaxLength: intPtr(3), Unit: UnitBytes},
+			expected:      "wor\n\n[Content truncated. Use start_index=9 to get more content.]",
+			expectedStart: 6,
+			expectedEnd:   9,
+			expectedTotal: 11,
+		},
+		{
+			name:          "bytes back off to a character boundary",
+			content:       "aé",
+			opts:          PageOptions{MaxLength: intPtr(2), Unit: UnitBytes},
+			expected:      "a\n\n[Content truncated. Use start_index=1 to get more content.]",
+			expectedStart: 0,
+			expectedEnd:   1,
+			expectedTotal: 3,
+		},
+		{
+			name:          "bytes return a character longer than the limit",
+			content:       "ツa",
+			opts:          PageOptions{MaxLength: intPtr(1), Unit: UnitBytes},
+			expected:      "ツ\n\n[Content truncated. Use start_index=3 to get more content.]",
+			expectedStart: 0,
+			expectedEnd:   3,
+			expectedTotal: 4,
+		},
+		{
+			name:          "zero max length returns an empty window",
+			content:       "héllo",
+			opts:          PageOptions{StartIndex: intPtr(1), MaxLength: intPtr(0), BoundaryAware: true},
+			expected:      "\n\n[Content truncated. Use start_index=1 to get more content.]",
+			expectedStart: 1,
+			expectedEnd:   1,
+			expectedTotal: 5,
+		},
+		{
+			name:          "zero max length in bytes returns an empty window",
+			content:       "ツa",
+			opts:          PageOptions{MaxLength: intPtr(0), Unit: UnitBytes},
+			expected:      "\n\n[Content truncated. Use start_index=0 to get more content.]",
+			expectedStart: 0,
+			expectedEnd:   0,
+			expectedTotal: 4,
+		},
+		{
+			name:          "bytes start inside a character",
+			content:       "ツ",
+			opts:          PageOptions{StartIndex: intPtr(1), MaxLength: intPtr(1), Unit: UnitBytes},
+			expected:      "ツ",
+			expectedStart: 0,
+			expectedEnd:   3,
+			expectedTotal: 3,
+		},
+		{
+			name:          "tokens",
+			content:       "The quick brown fox",
+			opts:          PageOptions{StartIndex: intPtr(1), MaxLength: intPtr(2), Unit: UnitTokens},
+			expected:      " quick brown\n\n[Content truncated. Use start_index=3 to get more content.]",
+			expectedStart: 1,
+			expectedEnd:   3,
+			expectedTotal: 4,
+		},
+		{
+			name:          "tokens never split characters",
+			content:       "日本語のテキスト",
+			opts:          PageOptions{StartIndex: intPtr(3), MaxLength: intPtr(2), Unit: UnitTokens},
+			expected:      "のテ\n\n[Content truncated. Use start_index=5 to get more content.]",
+			expectedStart: 3,
+			expectedEnd:   5,
+			expectedTotal: 8,
+		},
+		{
+			name:          "negative start index",
+			content:       "abc",
+			opts:          PageOptions{StartIndex: intPtr(-5)},
+			expected:      "abc",
+			expectedStart: 0,
+			expectedEnd:   3,
+			expectedTotal: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := processor.Paginate(tt.content, tt.opts)
+			if page.Content != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, page.Content)
+			}
+			if page.Start != tt.expectedStart || page.End != tt.expectedEnd || page.Total != tt.expectedTotal {
+				t.Errorf("expected range %d-%d of %d, got %d-%d of %d",
+					tt.expectedStart, tt.expectedEnd, tt.expectedTotal, page.Start, page.End, page.Total)
+			}
+			if !utf8.ValidString(page.Content) {
+				t.Errorf("expected valid UTF-8, got %q", page.Content)
+			}
+		})
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	for _, name := range []string{"", "chars", "BYTES", "tokens"} {
+		if _, err := ParseUnit(name); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", name, err)
+		}
+	}
+	if _, err := ParseUnit("words"); err == nil {
+		t.Error("expected unknown unit to be rejected")
+	}
+}
+
+func TestProcessHTMLDocument(t *testing.T) {
+	processor := NewContentProcessor()
+
+	page := `<html><head><title>Release Notes</title>
+<meta name="author" content="Jane Doe">
+<meta property="article:published_time" content="2024-03-01T10:00:00Z">
+</head><body><article><h1>Release Notes</h1>
+<p>This release brings many improvements to the fetching pipeline and its documentation.</p>
+</article></body></html>`
+
+	doc, err := processor.ProcessHTMLDocument(context.Background(), page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.Title != "Release Notes" {
+		t.Errorf("expected title %q, got %q", "Release Notes", doc.Title)
+	}
+	if doc.Byline != "Jane Doe" {
+		t.Errorf("expected byline %q, got %q", "Jane Doe", doc.Byline)
+	}
+	if doc.Published == nil || !doc.Published.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected published time 2024-03-01T10:00:00Z, got %v", doc.Published)
+	}
+	if !strings.Contains(doc.Content, "many improvements") {
+		t.Errorf("expected markdown content, got %q", doc.Content)
+	}
+}
+
+func TestProcessHTMLContextCancelled(t *testing.T) {
+	processor := NewContentProcessor()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := processor.ProcessHTMLContext(ctx, "<html><body><h1>Title</h1><p>Content</p></body></html>")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 // intPtr returns a pointer to an int
 func intPtr(i int) *int {
 	return &i
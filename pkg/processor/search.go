@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SearchOptions controls how Search reports matches
+type SearchOptions struct {
+	// Context is the number of lines shown before and after each matching line
+	Context int
+	// MaxMatches caps the matches returned; zero returns them all
+	MaxMatches int
+	// Unit measures the offsets of matches; empty means characters
+	Unit Unit
+}
+
+// Match is a line of content matching a search query
+type Match struct {
+	// Line is the 1-based number of the line the match starts on
+	Line int `json:"line"`
+	// Text is the first matching text on the line
+	Text string `json:"text"`
+	// Offset is where the match starts
+	Offset int `json:"offset"`
+	// StartIndex is where the snippet starts, usable as a start index to read on from there
+	StartIndex int `json:"start_index"`
+	// Snippet is the matching lines with their surrounding context lines
+	Snippet string `json:"snippet"`
+}
+
+// SearchPattern compiles a search query, matched literally unless regex is
+// set. Queries match case-insensitively unless caseSensitive is set.
+func SearchPattern(query string, regex, caseSensitive bool) (*regexp.Regexp, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query must not be empty")
+	}
+	if !regex {
+		query = regexp.QuoteMeta(query)
+	}
+	if !caseSensitive {
+		query = "(?i)" + query
+	}
+	pattern, err := regexp.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	return pattern, nil
+}
+
+// Search finds the lines of content matching pattern, reporting one match per
+// line with opts.Context lines around it. It returns the matches, up to
+// opts.MaxMatches, and the total number of matching lines.
+func Search(content string, pattern *regexp.Regexp, opts SearchOptions) ([]Match, int) {
+	lines := lineStarts(content)
+	offsets := newUnitOffsets(content, opts.Unit)
+
+	matches := []Match{}
+	total := 0
+	lastLine := -1
+	for _, loc := range pattern.FindAllStringIndex(content, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		line := lineIndex(lines, loc[0])
+		if line == lastLine {
+			continue
+		}
+		lastLine = line
+		total++
+		if opts.MaxMatches > 0 && len(matches) >= opts.MaxMatches {
+			continue
+		}
+
+		first := max(0, line-opts.Context)
+		last := min(len(lines)-1, lineIndex(lines, loc[1]-1)+opts.Context)
+		end := len(content)
+		if last+1 < len(lines) {
+			end = lines[last+1]
+		}
+
+		matches = append(matches, Match{
+			Line:       line + 1,
+			Text:       content[loc[0]:loc[1]],
+			Offset:     offsets.unitIndex(loc[0]),
+			StartIndex: offsets.unitIndex(lines[first]),
+			Snippet:    strings.TrimRight(content[lines[first]:end], "\n"),
+		})
+	}
+	return matches, total
+}
+
+// lineStarts returns the byte offset of the start of each line
+func lineStarts(content string) []int {
+	starts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' && i+1 < len(content) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineIndex returns the index of the line holding a byte offset
+func lineIndex(starts []int, offset int) int {
+	return sort.SearchInts(starts, offset+1) - 1
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+const searchDoc = `# API
+
+## Parameters
+
+- timeout: seconds to wait
+- retries: attempts before giving up
+
+## Errors
+
+A Timeout error is returned when the timeout expires.
+Ünïcode line with timeout.
+`
+
+func TestSearchPattern(t *testing.T) {
+	literal, err := SearchPattern("a.b", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !literal.MatchString("A.B") || literal.MatchString("axb") {
+		t.Error("expected a literal, case-insensitive pattern")
+	}
+
+	regex, err := SearchPattern(`a.b`, true, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regex.MatchString("axb") || regex.MatchString("AXB") {
+		t.Error("expected a case-sensitive regular expression")
+	}
+
+	if _, err := SearchPattern("(", true, false); err == nil {
+		t.Error("expected an invalid regular expression to be rejected")
+	}
+	if _, err := SearchPattern("", false, false); err == nil {
+		t.Error("expected an empty query to be rejected")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	pattern, _ := SearchPattern("timeout", false, false)
+	matches, total := Search(searchDoc, pattern, SearchOptions{Context: 1})
+
+	if total != 3 || len(matches) != 3 {
+		t.Fatalf("expected one match per line on 3 lines, got %d of %d: %+v", len(matches), total, matches)
+	}
+
+	first := matches[0]
+	if first.Line != 5 || first.Text != "timeout" || first.Snippet != "\n- timeout: seconds to wait\n- retries: attempts before giving up" {
+		t.Errorf("unexpected first match: %+v", first)
+	}
+	if !strings.HasPrefix(searchDoc[first.Offset:], "timeout:") || first.StartIndex != strings.Index(searchDoc, "\n- timeout") {
+		t.Errorf("unexpected offsets: %+v", first)
+	}
+
+	if second := matches[1]; second.Text != "Timeout" || second.Line != 10 {
+		t.Errorf("expected the first match on the line to be reported, got %+v", second)
+	}
+
+	last := matches[2]
+	if !strings.HasSuffix(last.Snippet, "Ünïcode line with timeout.") {
+		t.Errorf("unexpected last snippet: %q", last.Snippet)
+	}
+	bytesMatches, _ := Search(searchDoc, pattern, SearchOptions{Unit: UnitBytes})
+	if bytesMatches[2].Offset != last.Offset+2 {
+		t.Errorf("expected byte offsets to count the accented characters, got %d and %d", bytesMatches[2].Offset, last.Offset)
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	pattern, _ := SearchPattern(`(?m)^#+ \w+`, true, false)
+
+	matches, total := Search(searchDoc, pattern, SearchOptions{MaxMatches: 2})
+	if total != 3 || len(matches) != 2 {
+		t.Errorf("expected 2 of 3 headings, got %d of %d", len(matches), total)
+	}
+	if matches[0].Snippet != "# API" {
+		t.Errorf("expected a snippet without context, got %q", matches[0].Snippet)
+	}
+
+	empty, _ := SearchPattern(`z*`, true, false)
+	if matches, total := Search(searchDoc, empty, SearchOptions{}); total != 0 || len(matches) != 0 {
+		t.Errorf("expected empty matches to be ignored, got %+v", matches)
+	}
+}
@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// selectionSeparator separates the markdown of the elements a selection matches
+const selectionSeparator = "\n\n---\n\n"
+
+// Selection picks the elements of an HTML page to convert, in place of the
+// article readability would pick. Elements are matched with a CSS selector or
+// an XPath expression, and elements matching an exclude selector are removed
+// from them.
+type Selection struct {
+	expr    string
+	css     cascadia.SelectorGroup
+	xpath   *xpath.Expr
+	exclude []cascadia.SelectorGroup
+	// excludeExprs are the selectors exclude was compiled from
+	excludeExprs []string
+}
+
+// ParseSelection compiles a CSS selector or XPath expression, at most one of
+// which may be set, and the CSS selectors of the elements to leave out. With
+// only exclusions, the whole body is selected. It returns nil when nothing is set.
+func ParseSelection(selector, xpathExpr string, exclude []string) (*Selection, error) {
+	selector, xpathExpr = strings.TrimSpace(selector), strings.TrimSpace(xpathExpr)
+	if selector != "" && xpathExpr != "" {
+		return nil, errors.New("selector and xpath cannot be used together")
+	}
+	if selector == "" && xpathExpr == "" && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	s := &Selection{}
+	switch {
+	case xpathExpr != "":
+		expr, err := xpath.Compile(xpathExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath %q: %v", xpathExpr, err)
+		}
+		s.expr, s.xpath = xpathExpr, expr
+	default:
+		if selector == "" {
+			selector = "body"
+		}
+		group, err := cascadia.ParseGroup(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+		s.expr, s.css = selector, group
+	}
+
+	for _, excluded := range exclude {
+		group, err := cascadia.ParseGroup(excluded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude selector %q: %v", excluded, err)
+		}
+		s.exclude = append(s.exclude, group)
+		s.excludeExprs = append(s.excludeExprs, excluded)
+	}
+
+	return s, nil
+}
+
+// String returns the selector or XPath expression the selection matches with
+func (s *Selection) String() string {
+	return s.expr
+}
+
+// key identifies the selection, telling CSS from XPath and including the exclusions
+func (s *Selection) key() string {
+	kind := "css"
+	if s.xpath != nil {
+		kind = "xpath"
+	}
+	return kind + ":" + s.expr + "\x00" + strings.Join(s.excludeExprs, "\x00")
+}
+
+// Select returns the matched elements in document order, leaving out those
+// nested in another match, with the excluded elements removed from them
+func (s *Selection) Select(doc *html.Node) []*html.Node {
+	var matches []*html.Node
+	if s.xpath != nil {
+		matches = htmlquery.QuerySelectorAll(doc, s.xpath)
+	} else {
+		matches = cascadia.QueryAll(doc, s.css)
+	}
+
+	selected := make(map[*html.Node]bool, len(matches))
+	var nodes []*html.Node
+	for _, n := range matches {
+		if !hasSelectedAncestor(n, selected) {
+			nodes = append(nodes, n)
+		}
+		selected[n] = true
+	}
+
+	for _, n := range nodes {
+		for _, group := range s.exclude {
+			for _, excluded := range cascadia.QueryAll(n, group) {
+				if excluded.Parent != nil {
+					excluded.Parent.RemoveChild(excluded)
+				}
+			}
+		}
+	}
+
+	return nodes
+}
+
+// hasSelectedAncestor reports whether one of a node's ancestors is in selected
+func hasSelectedAncestor(n *html.Node, selected map[*html.Node]bool) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if selected[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// convertSelection converts the elements a selection matches to markdown,
+// separated by horizontal rules
+func (p *ContentProcessor) convertSelection(doc *html.Node, selection *Selection) (string, error) {
+	nodes := selection.Select(doc)
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("selector %q matched nothing", selection)
+	}
+
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		var rendered strings.Builder
+		if err := html.Render(&rendered, n); err != nil {
+			return "", err
+		}
+
+		markdown, err := p.htmlConverter.ConvertString(rendered.String())
+		if err != nil {
+			return "", err
+		}
+		if markdown = strings.TrimSpace(markdown); markdown != "" {
+			parts = append(parts, markdown)
+		}
+	}
+
+	return strings.Join(parts, selectionSeparator), nil
+}
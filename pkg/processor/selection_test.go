@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const changelogPage = `<html><head><title>Changelog</title></head><body>
+<nav><a href="/">Home</a></nav>
+<div class="release"><h2>v2.0</h2><p>Breaking changes.</p><div class="ad">Buy now!</div></div>
+<div class="release"><h2>v1.1</h2><p>Bug fixes.</p><div class="release"><p>Nested release note.</p></div></div>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestProcessSelection(t *testing.T) {
+	processor := NewContentProcessor()
+
+	tests := []struct {
+		name     string
+		selector string
+		xpath    string
+		exclude  []string
+		expected string
+	}{
+		{
+			name:     "css selector with exclusions",
+			selector: ".release",
+			exclude:  []string{".ad"},
+			expected: "## v2.0\n\nBreaking changes.\n\n---\n\n## v1.1\n\nBug fixes.\n\nNested release note.",
+		},
+		{
+			name:     "xpath",
+			xpath:    "//div[@class='release']/h2",
+			expected: "## v2.0\n\n---\n\n## v1.1",
+		},
+		{
+			name:     "exclusions only",
+			exclude:  []string{"nav", "footer", ".ad"},
+			expected: "## v2.0\n\nBreaking changes.\n\n## v1.1\n\nBug fixes.\n\nNested release note.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := ParseSelection(tt.selector, tt.xpath, tt.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			doc, err := processor.Process(context.Background(), &Input{
+				Body:      []byte(changelogPage),
+				MediaType: "text/html",
+				Selection: selection,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.Content != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, doc.Content)
+			}
+			if doc.Title != "Changelog" {
+				t.Errorf("expected the page title, got %q", doc.Title)
+			}
+		})
+	}
+}
+
+func TestProcessSelectionNoMatch(t *testing.T) {
+	selection, _ := ParseSelection("#missing", "", nil)
+	_, err := NewContentProcessor().Process(context.Background(), &Input{
+		Body:      []byte(changelogPage),
+		MediaType: "text/html",
+		Selection: selection,
+	})
+	if err == nil || !strings.Contains(err.Error(), `selector "#missing" matched nothing`) {
+		t.Errorf("expected a no-match error, got %v", err)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		xpath    string
+		exclude  []string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "nothing set", wantNil: true},
+		{name: "selector", selector: "article > p"},
+		{name: "xpath", xpath: "//main//p"},
+		{name: "both", selector: "p", xpath: "//p", wantErr: true},
+		{name: "invalid selector", selector: "p[", wantErr: true},
+		{name: "invalid xpath", xpath: "//p[", wantErr: true},
+		{name: "invalid exclusion", selector: "p", exclude: []string{":nope("}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		selection, err := ParseSelection(tt.selector, tt.xpath, tt.exclude)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (selection == nil) != tt.wantNil {
+			t.Errorf("%s: selection = %v, wantNil %v", tt.name, selection, tt.wantNil)
+		}
+	}
+}
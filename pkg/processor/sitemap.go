@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sitemapDateLayouts are the W3C Datetime precisions allowed in lastmod
+var sitemapDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+	"2006-01",
+	"2006",
+}
+
+// SitemapURL is a page listed by a sitemap
+type SitemapURL struct {
+	URL        string   `json:"url"`
+	LastMod    string   `json:"lastmod,omitempty"`
+	ChangeFreq string   `json:"changefreq,omitempty"`
+	Priority   *float64 `json:"priority,omitempty"`
+}
+
+// Sitemap is a parsed sitemap: a URL set, or an index of further sitemaps
+type Sitemap struct {
+	URLs     []SitemapURL
+	Sitemaps []string
+}
+
+// SitemapOptions bounds the decompression of gzipped sitemaps
+type SitemapOptions struct {
+	// MaxSize caps the uncompressed size of a gzipped sitemap; zero or less disables the limit
+	MaxSize int64
+	// Truncate parses the entries within MaxSize of a larger sitemap instead of rejecting it
+	Truncate bool
+}
+
+// sitemapXML covers both the urlset and the sitemapindex documents
+type sitemapXML struct {
+	URLs []struct {
+		Loc        string `xml:"loc"`
+		LastMod    string `xml:"lastmod"`
+		ChangeFreq string `xml:"changefreq"`
+		Priority   string `xml:"priority"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// ParseSitemap parses an XML sitemap or sitemap index, or a text sitemap
+// listing one URL per line. Gzipped sitemaps are decompressed first, within
+// the options' size limit.
+func ParseSitemap(body []byte, opts SitemapOptions) (*Sitemap, error) {
+	truncated := false
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		var err error
+		if body, truncated, err = gunzip(body, opts); err != nil {
+			return nil, err
+		}
+	}
+	if truncated {
+		// Drop the line the limit cut through
+		body = body[:bytes.LastIndexByte(body, '\n')+1]
+	}
+
+	switch xmlRoot(body) {
+	case "urlset", "sitemapindex":
+	case "":
+		if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+			return parseTextSitemap(body), nil
+		}
+		return nil, errors.New("not a sitemap")
+	default:
+		return nil, errors.New("not a sitemap")
+	}
+
+	// A truncated sitemap keeps the entries that were decoded in full
+	var parsed sitemapXML
+	if err := newXMLDecoder(body).Decode(&parsed); err != nil && !truncated {
+		return nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+
+	sitemap := &Sitemap{}
+	for _, entry := range parsed.Sitemaps {
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			sitemap.Sitemaps = append(sitemap.Sitemaps, loc)
+		}
+	}
+	for _, entry := range parsed.URLs {
+		loc := strings.TrimSpace(entry.Loc)
+		if loc == "" {
+			continue
+		}
+		u := SitemapURL{
+			URL:        loc,
+			LastMod:    strings.TrimSpace(entry.LastMod),
+			ChangeFreq: strings.TrimSpace(entry.ChangeFreq),
+		}
+		if priority, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64); err == nil {
+			u.Priority = &priority
+		}
+		sitemap.URLs = append(sitemap.URLs, u)
+	}
+	return sitemap, nil
+}
+
+// ParseLastMod parses a sitemap lastmod date, returning the zero time if it is
+// missing or unrecognized
+func ParseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range sitemapDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseTextSitemap collects the http(s) URLs of a text sitemap
+func parseTextSitemap(body []byte) *Sitemap {
+	sitemap := &Sitemap{}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			sitemap.URLs = append(sitemap.URLs, SitemapURL{URL: line})
+		}
+	}
+	return sitemap
+}
+
+// gunzip decompresses a gzipped sitemap within the options' size limit,
+// reporting whether it was truncated
+func gunzip(body []byte, opts SitemapOptions) ([]byte, bool, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid gzipped sitemap: %w", err)
+	}
+	defer reader.Close()
+
+	var src io.Reader = reader
+	if opts.MaxSize > 0 {
+		src = io.LimitReader(reader, opts.MaxSize+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid gzipped sitemap: %w", err)
+	}
+	if opts.MaxSize <= 0 || int64(len(data)) <= opts.MaxSize {
+		return data, false, nil
+	}
+	if !opts.Truncate {
+		return nil, false, fmt.Errorf("sitemap exceeds %d bytes uncompressed", opts.MaxSize)
+	}
+	return data[:opts.MaxSize], true, nil
+}
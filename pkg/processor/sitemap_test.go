@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSitemap(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc> https://example.com/docs/ </loc>
+    <lastmod>2024-05-01</lastmod>
+    <changefreq>weekly</changefreq>
+    <priority>0.8</priority>
+  </url>
+  <url><loc>https://example.com/blog/?a=1&amp;b=2</loc></url>
+  <url><loc></loc></url>
+</urlset>`)
+
+	sitemap, err := ParseSitemap(body, SitemapOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sitemap.URLs) != 2 || len(sitemap.Sitemaps) != 0 {
+		t.Fatalf("expected two URLs, got %+v", sitemap)
+	}
+	first := sitemap.URLs[0]
+	if first.URL != "https://example.com/docs/" || first.LastMod != "2024-05-01" || first.ChangeFreq != "weekly" ||
+		first.Priority == nil || *first.Priority != 0.8 {
+		t.Errorf("unexpected first URL %+v", first)
+	}
+	if sitemap.URLs[1].URL != "https://example.com/blog/?a=1&b=2" || sitemap.URLs[1].Priority != nil {
+		t.Errorf("unexpected second URL %+v", sitemap.URLs[1])
+	}
+}
+
+func TestParseSitemapIndex(t *testing.T) {
+	body := []byte(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/a.xml</loc><lastmod>2024-01-01</lastmod></sitemap>
+  <sitemap><loc>https://example.com/b.xml.gz</loc></sitemap>
+</sitemapindex>`)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(body)
+	writer.Close()
+
+	sitemap, err := ParseSitemap(compressed.Bytes(), SitemapOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sitemap.Sitemaps) != 2 || sitemap.Sitemaps[1] != "https://example.com/b.xml.gz" || len(sitemap.URLs) != 0 {
+		t.Errorf("expected two child sitemaps, got %+v", sitemap)
+	}
+}
+
+func TestParseSitemapSizeLimit(t *testing.T) {
+	body := "<urlset>\n<url><loc>https://example.com/a</loc></url>\n<url><loc>https://example.com/b</loc></url>\n" +
+		"<url><loc>https://example.com/c</loc></url>\n</urlset>\n"
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(body))
+	writer.Close()
+
+	// The limit falls inside the third URL
+	limit := int64(strings.Index(body, "/c<"))
+	if _, err := ParseSitemap(compressed.Bytes(), SitemapOptions{MaxSize: limit}); err == nil {
+		t.Error("expected a sitemap over the limit to be rejected")
+	}
+
+	sitemap, err := ParseSitemap(compressed.Bytes(), SitemapOptions{MaxSize: limit, Truncate: true})
+	if err != nil || len(sitemap.URLs) != 2 || sitemap.URLs[1].URL != "https://example.com/b" {
+		t.Errorf("expected the two URLs within the limit, got %+v, %v", sitemap, err)
+	}
+
+	sitemap, err = ParseSitemap(compressed.Bytes(), SitemapOptions{})
+	if err != nil || len(sitemap.URLs) != 3 {
+		t.Errorf("expected no limit to keep every URL, got %+v, %v", sitemap, err)
+	}
+}
+
+func TestParseSitemapText(t *testing.T) {
+	sitemap, err := ParseSitemap([]byte("https://example.com/a\n\nnot a url\r\nhttp://example.com/b\n"), SitemapOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sitemap.URLs) != 2 || sitemap.URLs[1].URL != "http://example.com/b" {
+		t.Errorf("expected two URLs, got %+v", sitemap.URLs)
+	}
+}
+
+func TestParseSitemapRejectsOtherDocuments(t *testing.T) {
+	for _, body := range []string{`<rss><channel></channel></rss>`, `<html><body>Not found</body></html>`, "\x1f\x8bnot gzip"} {
+		if _, err := ParseSitemap([]byte(body), SitemapOptions{}); err == nil {
+			t.Errorf("expected %q to be rejected", body)
+		}
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	tests := map[string]time.Time{
+		"2024-05-01":             time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01T10:30+02:00": time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+		"2024-05-01T10:30:15.5Z": time.Date(2024, 5, 1, 10, 30, 15, 5e8, time.UTC),
+		"2024-05":                time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		" 2024-05-01T10:30:15 ":  time.Date(2024, 5, 1, 10, 30, 15, 0, time.UTC),
+		"last tuesday":           {},
+		"":                       {},
+	}
+
+	for value, expected := range tests {
+		if got := ParseLastMod(value); !got.Equal(expected) {
+			t.Errorf("ParseLastMod(%q) = %v, expected %v", value, got, expected)
+		}
+	}
+}
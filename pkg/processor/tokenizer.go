@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sub-token sizes, in runes, used to approximate how a byte-pair encoding splits
+// longer runs. They are tuned so that English prose, code and CJK text land close
+// to the counts produced by common LLM tokenizers.
+const (
+	wordChunkRunes       = 6
+	digitChunkRunes      = 3
+	punctuationChunkRune = 2
+	whitespaceChunkRunes = 16
+)
+
+// runeClass groups runes that a BPE pre-tokenizer keeps together
+type runeClass int
+
+const (
+	classLetter runeClass = iota
+	classDigit
+	classIdeograph
+	classSpace
+	classPunctuation
+)
+
+// classify returns the pre-tokenizer class of a rune
+func classify(r rune) runeClass {
+	switch {
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r),
+		unicode.Is(unicode.Hangul, r), unicode.Is(unicode.Thai, r), unicode.IsSymbol(r) && r > 0x2000:
+		return classIdeograph
+	case unicode.IsLetter(r), unicode.IsMark(r):
+		return classLetter
+	case unicode.IsDigit(r):
+		return classDigit
+	case unicode.IsSpace(r):
+		return classSpace
+	default:
+		return classPunctuation
+	}
+}
+
+// tokenBoundaries estimates where a BPE tokenizer would place token boundaries.
+// It pre-tokenizes like GPT-style tokenizers (words with their leading space,
+// digit groups, punctuation runs, whitespace runs), then splits long pieces into
+// fixed-size sub-tokens. Ideographs and emoji count as one token each. The result
+// holds the byte offset of every token start followed by len(s), so it is always
+// safe to slice s between any two consecutive entries.
+func tokenBoundaries(s string) []int {
+	boundaries := make([]int, 0, len(s)/3+1)
+
+	i := 0
+	for i < len(s) {
+		boundaries = append(boundaries, i)
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		// A single space is merged into the word, number or symbol run that follows it
+		if r == ' ' && beforeWord(s[i+size:]) {
+			i += size
+			r, size = utf8.DecodeRuneInString(s[i:])
+		}
+
+		class := classify(r)
+		if class == classIdeograph {
+			i += size
+			continue
+		}
+
+		limit := chunkRunes(class)
+		count := 0
+		for i < len(s) && count < limit {
+			next, nextSize := utf8.DecodeRuneInString(s[i:])
+			if classify(next) != class {
+				break
+			}
+			if class == classSpace && count > 0 {
+				// Keep newlines apart from the indentation that follows them
+				if next != '\n' && s[i-1] == '\n' {
+					break
+				}
+				// Leave the last space of a run to merge with the word after it
+				if next == ' ' && beforeWord(s[i+nextSize:]) {
+					break
+				}
+			}
+			i += nextSize
+			count++
+		}
+	}
+
+	return append(boundaries, len(s))
+}
+
+// beforeWord reports whether s starts with a rune a preceding space merges into
+func beforeWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	class := classify(r)
+	return class != classSpace && class != classIdeograph
+}
+
+// chunkRunes returns the number of runes of a class that make up one estimated token
+func chunkRunes(class runeClass) int {
+	switch class {
+	case classLetter:
+		return wordChunkRunes
+	case classDigit:
+		return digitChunkRunes
+	case classSpace:
+		return whitespaceChunkRunes
+	default:
+		return punctuationChunkRune
+	}
+}
+
+// CountTokens returns the estimated number of LLM tokens in s
+func CountTokens(s string) int {
+	return len(tokenBoundaries(s)) - 1
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"empty", "", nil},
+		{"words keep their leading space", "Hello world", []string{"Hello", " world"}},
+		{"long words are split", "internationalization", []string{"intern", "ationa", "lizati", "on"}},
+		{"digits are grouped in threes", "1234567", []string{"123", "456", "7"}},
+		{"punctuation runs", "a, b!?!", []string{"a", ",", " b", "!?", "!"}},
+		{"ideographs are single tokens", "日本語", []string{"日", "本", "語"}},
+		{"emoji are single tokens", "hi 👋", []string{"hi", " ", "👋"}},
+		{"newlines are split from indentation", "a\n\n    b", []string{"a", "\n\n", "   ", " b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			boundaries := tokenBoundaries(tt.input)
+
+			var tokens []string
+			for i := 0; i+1 < len(boundaries); i++ {
+				tokens = append(tokens, tt.input[boundaries[i]:boundaries[i+1]])
+			}
+
+			if strings.Join(tokens, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected tokens %q, got %q", tt.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestCountTokensEstimate(t *testing.T) {
+	// Typical English prose averages roughly four characters per token
+	prose := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	count := CountTokens(prose)
+	if count < len(prose)/6 || count > len(prose)/3 {
+		t.Errorf("expected roughly %d tokens for %d characters, got %d", len(prose)/4, len(prose), count)
+	}
+}
+
+func TestTokenBoundariesCoverInput(t *testing.T) {
+	input := "Mixed 日本語 text, numbers 12345 and emoji 🎉\n\tdone"
+	boundaries := tokenBoundaries(input)
+
+	if boundaries[0] != 0 || boundaries[len(boundaries)-1] != len(input) {
+		t.Fatalf("expected boundaries to span the input, got %v", boundaries)
+	}
+	for i := 1; i < len(boundaries); i++ {
+		if boundaries[i] <= boundaries[i-1] {
+			t.Fatalf("expected strictly increasing boundaries, got %v", boundaries)
+		}
+	}
+}
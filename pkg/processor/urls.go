@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// urlAttributes lists the attributes holding a single URL, by element
+var urlAttributes = map[atom.Atom][]string{
+	atom.A:      {"href"},
+	atom.Area:   {"href"},
+	atom.Img:    {"src"},
+	atom.Source: {"src"},
+	atom.Video:  {"src", "poster"},
+	atom.Audio:  {"src"},
+	atom.Track:  {"src"},
+	atom.Iframe: {"src"},
+	atom.Embed:  {"src"},
+	atom.Object: {"data"},
+}
+
+// documentBase returns the URL relative references in a document resolve
+// against: the page URL, overridden by the document's <base href>. It returns
+// nil when neither gives an absolute URL.
+func documentBase(doc *html.Node, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		base = &url.URL{}
+	}
+	if href := findBase(doc); href != "" {
+		if resolved, err := base.Parse(href); err == nil {
+			base = resolved
+		}
+	}
+	if !base.IsAbs() {
+		return nil
+	}
+	return base
+}
+
+// absolutizeURLs rewrites the link, image and media URLs of a document to
+// absolute URLs. In-page anchors and URLs that do not parse are left alone.
+func absolutizeURLs(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		names := urlAttributes[n.DataAtom]
+		for i := range n.Attr {
+			a := &n.Attr[i]
+			if a.Namespace != "" {
+				continue
+			}
+			switch key := strings.ToLower(a.Key); {
+			case key == "srcset" && (n.DataAtom == atom.Img || n.DataAtom == atom.Source):
+				a.Val = absoluteSrcset(base, a.Val)
+			case slices.Contains(names, key):
+				a.Val = absoluteURL(base, a.Val)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		absolutizeURLs(c, base)
+	}
+}
+
+// absoluteURL resolves a reference against base, keeping in-page anchors as they are
+func absoluteURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ref
+	}
+	resolved, err := base.Parse(trimmed)
+	if err != nil {
+		return ref
+	}
+	return resolved.String()
+}
+
+// absoluteSrcset resolves each image candidate URL of a srcset attribute
+func absoluteSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = absoluteURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
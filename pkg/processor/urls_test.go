@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProcessAbsoluteURLs(t *testing.T) {
+	processor := NewContentProcessor()
+
+	article := `<html><body><article><h1>Guide</h1>
+<p>Start with the <a href="/docs/intro">introduction</a>, then read the <a href="../faq">FAQ</a>
+and the <a href="#setup">setup section</a>. This paragraph is long enough for readability to keep it.</p>
+<p><img src="img.png" alt="Diagram"> More text so the article has enough content to be extracted.</p>
+</article></body></html>`
+
+	tests := []struct {
+		name     string
+		body     string
+		url      string
+		absolute bool
+		expected []string
+	}{
+		{
+			name:     "readability article",
+			body:     article,
+			url:      "https://example.com/guide/start",
+			absolute: true,
+			expected: []string{"(https://example.com/docs/intro)", "(https://example.com/faq)", "(#setup)", "(https://example.com/guide/img.png)"},
+		},
+		{
+			name:     "base element",
+			body:     `<html><head><base href="https://cdn.example.org/a/"></head>` + article[len("<html>"):],
+			url:      "https://example.com/guide/start",
+			absolute: true,
+			expected: []string{"(https://cdn.example.org/docs/intro)", "(https://cdn.example.org/faq)", "(https://cdn.example.org/a/img.png)"},
+		},
+		{
+			name:     "page without an article",
+			body:     `<a href="next.html">Next</a>`,
+			url:      "https://example.com/list/",
+			absolute: true,
+			expected: []string{"[Next](https://example.com/list/next.html)"},
+		},
+		{
+			name:     "option off",
+			body:     article,
+			url:      "https://example.com/guide/start",
+			expected: []string{"(/docs/intro)", "(../faq)", "(img.png)"},
+		},
+		{
+			name:     "no page URL",
+			body:     article,
+			absolute: true,
+			expected: []string{"(/docs/intro)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := processor.Process(context.Background(), &Input{
+				Body:         []byte(tt.body),
+				MediaType:    "text/html",
+				URL:          tt.url,
+				AbsoluteURLs: tt.absolute,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(doc.Content, want) {
+					t.Errorf("expected %q in:\n%s", want, doc.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestAbsoluteSrcset(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/b")
+	got := absoluteSrcset(base, "small.jpg 480w,  /big.jpg 1080w")
+	expected := "https://example.com/a/small.jpg 480w, https://example.com/big.jpg 1080w"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
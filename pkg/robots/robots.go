@@ -2,102 +2,371 @@
 package robots
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// maxRobotsSize is the number of bytes of robots.txt that are parsed, per RFC 9309 section 2.5
+const maxRobotsSize = 500 * 1024
+
+// Cache defaults
+const (
+	// DefaultCacheTTL is how long a fetched robots.txt is reused when the response has no caching directives
+	DefaultCacheTTL = time.Hour
+	// DefaultErrorGrace is how long access is disallowed after robots.txt could not be retrieved
+	DefaultErrorGrace = 5 * time.Minute
+	// maxCacheTTL caps caching directives, per RFC 9309 section 2.4
+	maxCacheTTL = 24 * time.Hour
+	// minCacheTTL keeps a response that must not be cached long enough for the
+	// robots.txt lookups of a single fetch to share one download
+	minCacheTTL = 10 * time.Second
+	// pruneThreshold is the number of cached hosts above which expired entries are evicted
+	pruneThreshold = 1024
+)
+
+// ErrDisallowed is returned when robots.txt forbids access to a URL
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
 // Checker handles robots.txt validation for web crawling
 type Checker struct {
 	userAgent    string
+	productToken string
 	ignoreRobots bool
 	httpClient   *http.Client
+	cacheTTL     time.Duration
+	errorGrace   time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	cache    map[string]*cacheEntry
+	inflight map[string]*inflightFetch
+}
+
+// cacheEntry holds the robots.txt outcome for a single origin
+type cacheEntry struct {
+	robots  *robotsFile
+	err     error // set when robots.txt was unreachable, which disallows everything
+	expires time.Time
+}
+
+// inflightFetch lets concurrent lookups for the same origin share one request
+type inflightFetch struct {
+	done  chan struct{}
+	entry *cacheEntry
+}
+
+// Option configures a Checker
+type Option func(*Checker)
+
+// WithCacheTTL sets how long robots.txt is cached when the response carries no caching directives
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Checker) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithErrorGrace sets how long access is disallowed after a server or network error
+func WithErrorGrace(grace time.Duration) Option {
+	return func(c *Checker) {
+		c.errorGrace = grace
+	}
 }
 
 // NewChecker creates a new robots.txt checker
-func NewChecker(userAgent string, ignoreRobots bool, httpClient *http.Client) *Checker {
-	return &Checker{
+func NewChecker(userAgent string, ignoreRobots bool, httpClient *http.Client, opts ...Option) *Checker {
+	c := &Checker{
 		userAgent:    userAgent,
+		productToken: productToken(userAgent),
 		ignoreRobots: ignoreRobots,
 		httpClient:   httpClient,
+		cacheTTL:     DefaultCacheTTL,
+		errorGrace:   DefaultErrorGrace,
+		now:          time.Now,
+		cache:        make(map[string]*cacheEntry),
+		inflight:     make(map[string]*inflightFetch),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // IsAllowed checks if the URL can be accessed according to robots.txt
 func (c *Checker) IsAllowed(targetURL string) bool {
+	return c.IsAllowedContext(context.Background(), targetURL)
+}
+
+// IsAllowedContext is like IsAllowed but aborts fetching robots.txt when the context is done
+func (c *Checker) IsAllowedContext(ctx context.Context, targetURL string) bool {
+	return c.Check(ctx, targetURL) == nil
+}
+
+// Check returns nil if the URL can be accessed according to robots.txt, or an
+// error wrapping ErrDisallowed explaining why it cannot. If the context is done
+// before robots.txt is known, the context's error is returned instead.
+func (c *Checker) Check(ctx context.Context, targetURL string) error {
 	if c.ignoreRobots {
-		return true
+		return nil
 	}
 
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
-		return false
+		return fmt.Errorf("access to %s is %w: invalid URL: %v", targetURL, ErrDisallowed, err)
+	}
+
+	// The robots.txt file itself is always allowed
+	if parsedURL.EscapedPath() == "/robots.txt" {
+		return nil
+	}
+
+	entry, err := c.lookup(ctx, parsedURL)
+	if err != nil {
+		return fmt.Errorf("checking robots.txt for %s: %w", targetURL, err)
+	}
+	if entry.err != nil {
+		return fmt.Errorf("access to %s is %w: robots.txt is unreachable: %v", targetURL, ErrDisallowed, entry.err)
+	}
+
+	if !entry.robots.isAllowed(c.productToken, targetPath(parsedURL)) {
+		return fmt.Errorf("access to %s is %w", targetURL, ErrDisallowed)
+	}
+
+	return nil
+}
+
+// CrawlDelay returns the Crawl-delay that robots.txt requests for the URL's host,
+// or zero if none is declared, robots.txt is ignored or it could not be retrieved
+func (c *Checker) CrawlDelay(ctx context.Context, targetURL string) time.Duration {
+	if c.ignoreRobots {
+		return 0
+	}
+
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return 0
+	}
+
+	entry, err := c.lookup(ctx, parsedURL)
+	if err != nil || entry.err != nil {
+		return 0
+	}
+
+	return entry.robots.groupFor(c.productToken).crawlDelay
+}
+
+// Sitemaps returns the sitemap URLs that robots.txt for the URL's host lists,
+// whether or not robots.txt rules are ignored. A missing or unreachable
+// robots.txt lists none.
+func (c *Checker) Sitemaps(ctx context.Context, targetURL string) ([]string, error) {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
 	}
 
-	robotsContent, err := c.fetchRobotsContent(parsedURL)
+	entry, err := c.lookup(ctx, parsedURL)
 	if err != nil {
-		// If we can't fetch robots.txt, allow access
-		return true
+		return nil, fmt.Errorf("checking robots.txt for %s: %w", targetURL, err)
+	}
+	if entry.err != nil {
+		return nil, nil
+	}
+	return entry.robots.sitemaps, nil
+}
+
+// lookup returns the cached robots.txt outcome for the URL's origin, fetching it
+// when missing or expired. Concurrent lookups for the same origin wait for a
+// single shared request. If that request is abandoned because its caller's
+// context is done, the remaining callers retry rather than caching the failure.
+func (c *Checker) lookup(ctx context.Context, parsedURL *url.URL) (*cacheEntry, error) {
+	key := originKey(parsedURL)
+
+	for {
+		c.mu.Lock()
+		if entry, ok := c.cache[key]; ok && c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return entry, nil
+		}
+		if call, ok := c.inflight[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-call.done:
+				if call.entry != nil {
+					return call.entry, nil
+				}
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		call := &inflightFetch{done: make(chan struct{})}
+		c.inflight[key] = call
+		c.mu.Unlock()
+
+		entry, err := c.fetchRobots(ctx, parsedURL)
+
+		c.mu.Lock()
+		if entry != nil {
+			if len(c.cache) >= pruneThreshold {
+				c.pruneLocked()
+			}
+			c.cache[key] = entry
+		}
+		delete(c.inflight, key)
+		c.mu.Unlock()
+
+		call.entry = entry
+		close(call.done)
+
+		return entry, err
 	}
+}
 
-	return c.parseRobotsRules(robotsContent, parsedURL.Path)
+// pruneLocked evicts expired cache entries. The caller must hold c.mu.
+func (c *Checker) pruneLocked() {
+	now := c.now()
+	for key, entry := range c.cache {
+		if !now.Before(entry.expires) {
+			delete(c.cache, key)
+		}
+	}
 }
 
-// fetchRobotsContent retrieves the robots.txt file for a given URL
-func (c *Checker) fetchRobotsContent(parsedURL *url.URL) (string, error) {
+// fetchRobots retrieves and interprets robots.txt following RFC 9309 section 2.3.1:
+// a successful response is parsed, an unavailable (4xx) file allows everything,
+// and an unreachable (5xx or network error) file disallows everything for a grace period.
+// An error is returned only when the context is done, as that outcome must not be cached.
+func (c *Checker) fetchRobots(ctx context.Context, parsedURL *url.URL) (*cacheEntry, error) {
 	robotsURL := fmt.Sprintf("%s://%s/robots.txt", parsedURL.Scheme, parsedURL.Host)
 
-	req, err := http.NewRequest("GET", robotsURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
 	if err != nil {
-		return "", err
+		return c.unreachable(robotsURL, err), nil
 	}
 
 	req.Header.Set("User-Agent", c.userAgent)
 
 	resp, err := c.httpClient.Do(req)
-	if err != nil || resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch robots.txt")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return c.unreachable(robotsURL, err), nil
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return c.unreachable(robotsURL, err), nil
+		}
+		return &cacheEntry{
+			robots:  parseRobots(string(body)),
+			expires: c.now().Add(c.ttlFor(resp.Header)),
+		}, nil
+	// Too Many Requests signals an overloaded server rather than a missing file
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return c.unreachable(robotsURL, fmt.Errorf("HTTP %d", resp.StatusCode)), nil
+	default:
+		return &cacheEntry{
+			robots:  &robotsFile{},
+			expires: c.now().Add(c.ttlFor(resp.Header)),
+		}, nil
 	}
+}
 
-	return string(body), nil
+// unreachable returns a cache entry that disallows everything for the error grace period
+func (c *Checker) unreachable(robotsURL string, err error) *cacheEntry {
+	log.Printf("robots.txt unreachable at %s, disallowing access for %s: %v", robotsURL, c.errorGrace, err)
+	return &cacheEntry{
+		err:     err,
+		expires: c.now().Add(c.errorGrace),
+	}
 }
 
-// parseRobotsRules parses robots.txt content and checks if access is allowed
-func (c *Checker) parseRobotsRules(robotsContent, targetPath string) bool {
-	userAgentPattern := regexp.MustCompile(`(?i)^User-agent:\s*(.+)$`)
-	disallowPattern := regexp.MustCompile(`(?i)^Disallow:\s*(.*)$`) // Allow empty disallow rules
+// ttlFor returns how long a robots.txt response may be cached, honoring
+// Cache-Control directives within the bounds of 10 seconds and 24 hours
+func (c *Checker) ttlFor(header http.Header) time.Duration {
+	ttl := c.cacheTTL
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return minCacheTTL
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds >= 0 {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
 
-	lines := strings.Split(robotsContent, "\n")
-	var currentUserAgents []string
+	return max(min(ttl, maxCacheTTL), minCacheTTL)
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+// originKey returns the cache key for a URL's scheme, host and port
+func originKey(parsedURL *url.URL) string {
+	scheme := strings.ToLower(parsedURL.Scheme)
 
-		if userAgentMatch := userAgentPattern.FindStringSubmatch(line); userAgentMatch != nil {
-			userAgent := strings.TrimSpace(userAgentMatch[1])
-			if userAgent == "*" || strings.Contains(c.userAgent, userAgent) {
-				currentUserAgents = append(currentUserAgents, userAgent)
-			}
-		} else if disallowMatch := disallowPattern.FindStringSubmatch(line); disallowMatch != nil && len(currentUserAgents) > 0 {
-			disallowPath := strings.TrimSpace(disallowMatch[1])
-			// Empty disallow means allow everything for this user agent
-			if disallowPath == "" {
-				continue
-			}
-			if disallowPath == "/" || strings.HasPrefix(targetPath, disallowPath) {
-				return false
-			}
+	port := parsedURL.Port()
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
 		}
 	}
 
-	return true
+	return scheme + "://" + strings.ToLower(parsedURL.Hostname()) + ":" + port
+}
+
+// targetPath returns the path and query of a URL in the form matched against robots.txt rules
+func targetPath(parsedURL *url.URL) string {
+	path := parsedURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+	return path
+}
+
+// productToken extracts the crawler name used for group matching from a User-Agent
+// string, e.g. "MCPFetchBot" from "Mozilla/5.0 (compatible; MCPFetchBot/1.0)"
+func productToken(userAgent string) string {
+	candidate := userAgent
+	if _, after, found := strings.Cut(userAgent, "compatible;"); found {
+		candidate = after
+	}
+	candidate = strings.TrimSpace(candidate)
+
+	end := strings.IndexFunc(candidate, func(r rune) bool {
+		return !isProductTokenChar(r)
+	})
+	if end >= 0 {
+		candidate = candidate[:end]
+	}
+
+	return candidate
+}
+
+// isProductTokenChar reports whether r may appear in a product token, per RFC 9309 section 2.2.1
+func isProductTokenChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '-' || r == '_'
 }
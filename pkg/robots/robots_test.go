@@ -1,8 +1,13 @@
 package robots
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -74,9 +79,30 @@ func TestIsAllowed(t *testing.T) {
 			name:         "disallowed path for all user agents",
 			targetURL:    server.URL + "/private/secret",
 			ignoreRobots: false,
+			userAgent:    "OtherBot/1.0",
+			expected:     false,
+		},
+		{
+			name:         "specific group replaces the catch-all group",
+			targetURL:    server.URL + "/private/secret",
+			ignoreRobots: false,
 			userAgent:    "TestBot/1.0",
+			expected:     true,
+		},
+		{
+			name:         "product token is matched case-insensitively",
+			targetURL:    server.URL + "/blocked/page",
+			ignoreRobots: false,
+			userAgent:    "Mozilla/5.0 (compatible; testbot/2.1)",
 			expected:     false,
 		},
+		{
+			name:         "robots.txt itself is always allowed",
+			targetURL:    server.URL + "/robots.txt",
+			ignoreRobots: false,
+			userAgent:    "OtherBot/1.0",
+			expected:     true,
+		},
 		{
 			name:         "disallowed path for specific user agent",
 			targetURL:    server.URL + "/blocked/page",
@@ -85,11 +111,11 @@ func TestIsAllowed(t *testing.T) {
 			expected:     false,
 		},
 		{
-			name:         "URL with no robots.txt allows access",
+			name:         "unreachable robots.txt disallows access",
 			targetURL:    "http://nonexistent-host-12345.invalid/page",
 			ignoreRobots: false,
 			userAgent:    "TestBot/1.0",
-			expected:     true, // Can't fetch robots.txt, so allow access
+			expected:     false, // Can't reach robots.txt, so assume complete disallow
 		},
 	}
 
@@ -105,9 +131,6 @@ func TestIsAllowed(t *testing.T) {
 }
 
 func TestParseRobotsRules(t *testing.T) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	checker := NewChecker("TestBot/1.0", false, client)
-
 	tests := []struct {
 		name          string
 		robotsContent string
@@ -152,10 +175,284 @@ Disallow: /anything`,
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := checker.parseRobotsRules(tt.robotsContent, tt.targetPath)
+			result := parseRobots(tt.robotsContent).isAllowed("TestBot", tt.targetPath)
 			if result != tt.expected {
 				t.Errorf("expected %v, got %v", tt.expected, result)
 			}
 		})
 	}
 }
+
+func TestProductToken(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"TestBot/1.0", "TestBot"},
+		{"Mozilla/5.0 (compatible; MCPFetchBot/1.0)", "MCPFetchBot"},
+		{"Mozilla/5.0 (compatible; Example-Bot_2/1.0; +https://example.com)", "Example-Bot_"},
+		{"curl/8.0", "curl"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			if result := productToken(tt.userAgent); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStatusHandling(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected bool
+	}{
+		{"not found allows all", http.StatusNotFound, true},
+		{"forbidden allows all", http.StatusForbidden, true},
+		{"server error disallows all", http.StatusInternalServerError, false},
+		{"service unavailable disallows all", http.StatusServiceUnavailable, false},
+		{"too many requests disallows all", http.StatusTooManyRequests, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			checker := NewChecker("TestBot/1.0", false, server.Client())
+			err := checker.Check(context.Background(), server.URL+"/page")
+
+			if tt.expected && err != nil {
+				t.Errorf("expected access to be allowed, got %v", err)
+			}
+			if !tt.expected && !errors.Is(err, ErrDisallowed) {
+				t.Errorf("expected ErrDisallowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("User-agent: *\nDisallow: /private/"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	checker := NewChecker("TestBot/1.0", false, server.Client(), WithCacheTTL(time.Minute))
+	checker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if checker.IsAllowed(server.URL + "/private/page") {
+			t.Error("expected private path to be disallowed")
+		}
+		if !checker.IsAllowed(server.URL + "/public/page") {
+			t.Error("expected public path to be allowed")
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 robots.txt request, got %d", got)
+	}
+
+	// Once the TTL elapses, robots.txt is fetched again
+	now = now.Add(2 * time.Minute)
+	checker.IsAllowed(server.URL + "/public/page")
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 robots.txt requests after expiry, got %d", got)
+	}
+}
+
+func TestCacheHonorsCacheControl(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		expected     time.Duration
+	}{
+		{"no directives uses default TTL", "", time.Hour},
+		{"max-age", "public, max-age=120", 2 * time.Minute},
+		{"no-store is kept for the minimum TTL", "no-store", 10 * time.Second},
+		{"no-cache is kept for the minimum TTL", "no-cache", 10 * time.Second},
+		{"max-age=0 is kept for the minimum TTL", "max-age=0", 10 * time.Second},
+		{"max-age is capped at 24 hours", "max-age=604800", 24 * time.Hour},
+	}
+
+	checker := NewChecker("TestBot/1.0", false, http.DefaultClient)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.cacheControl != "" {
+				header.Set("Cache-Control", tt.cacheControl)
+			}
+			if ttl := checker.ttlFor(header); ttl != tt.expected {
+				t.Errorf("expected TTL %s, got %s", tt.expected, ttl)
+			}
+		})
+	}
+}
+
+func TestUnreachableGracePeriod(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	checker := NewChecker("TestBot/1.0", false, server.Client(), WithErrorGrace(time.Minute))
+	checker.now = func() time.Time { return now }
+
+	if checker.IsAllowed(server.URL + "/page") {
+		t.Error("expected access to be disallowed while robots.txt is unreachable")
+	}
+
+	// The outcome is cached for the grace period even if the server recovers
+	failing.Store(false)
+	if checker.IsAllowed(server.URL + "/page") {
+		t.Error("expected access to stay disallowed during the grace period")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !checker.IsAllowed(server.URL + "/page") {
+		t.Error("expected access to be allowed after the grace period")
+	}
+}
+
+func TestConcurrentLookupsAreDeduplicated(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write([]byte("User-agent: *\nDisallow: /private/"))
+	}))
+	defer server.Close()
+
+	checker := NewChecker("TestBot/1.0", false, server.Client())
+
+	var wg sync.WaitGroup
+	results := make([]bool, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = checker.IsAllowed(server.URL + "/private/page")
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 robots.txt request, got %d", got)
+	}
+	for i, allowed := range results {
+		if allowed {
+			t.Errorf("lookup %d: expected private path to be disallowed", i)
+		}
+	}
+}
+
+func TestOriginKey(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://Example.com/page", "https://example.com:443"},
+		{"http://example.com/page", "http://example.com:80"},
+		{"http://example.com:8080/page", "http://example.com:8080"},
+		{"HTTPS://example.com:443/other", "https://example.com:443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			parsedURL, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key := originKey(parsedURL); key != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, key)
+			}
+		})
+	}
+}
+
+func TestCheckRespectsContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		w.Write([]byte("User-agent: *\nDisallow: /private/"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := NewChecker("TestBot/1.0", false, server.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := checker.Check(ctx, server.URL+"/page")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if errors.Is(err, ErrDisallowed) {
+		t.Error("expected cancellation not to be reported as a robots.txt disallow")
+	}
+
+	// The abandoned lookup must not be cached as unreachable
+	checker.mu.Lock()
+	_, cached := checker.cache[originKey(mustParseURL(t, server.URL))]
+	checker.mu.Unlock()
+	if cached {
+		t.Error("expected cancelled lookup not to be cached")
+	}
+}
+
+// mustParseURL parses a URL or fails the test
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return parsedURL
+}
+
+func TestSitemaps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /\n\nSitemap: https://example.com/sitemap.xml\n"))
+	}))
+	defer server.Close()
+
+	// Sitemaps are listed even when the rules are ignored
+	for _, ignore := range []bool{false, true} {
+		checker := NewChecker("TestBot/1.0", ignore, server.Client())
+		sitemaps, err := checker.Sitemaps(context.Background(), server.URL+"/page")
+		if err != nil || len(sitemaps) != 1 || sitemaps[0] != "https://example.com/sitemap.xml" {
+			t.Errorf("expected the listed sitemap with ignore=%v, got %v, %v", ignore, sitemaps, err)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	checker := NewChecker("TestBot/1.0", false, failing.Client())
+	if sitemaps, err := checker.Sitemaps(context.Background(), failing.URL+"/page"); err != nil || len(sitemaps) != 0 {
+		t.Errorf("expected no sitemaps from an unreachable robots.txt, got %v, %v", sitemaps, err)
+	}
+}
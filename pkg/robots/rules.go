@@ -0,0 +1,278 @@
+package robots
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is a single allow or disallow line within a group. The pattern is stored
+// with its percent-encoding normalized so it compares directly against normalized paths.
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// group is a set of rules that applies to one or more user agents
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// robotsFile is a parsed robots.txt document
+type robotsFile struct {
+	groups []*group
+	// sitemaps lists the Sitemap URLs, which apply to the whole file rather than to a group
+	sitemaps []string
+}
+
+// parseRobots parses robots.txt content following RFC 9309. Lines that are not
+// understood are ignored, and unknown records do not end the current group.
+func parseRobots(content string) *robotsFile {
+	robots := &robotsFile{}
+
+	var current *group
+	inRules := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line that follows rules starts a new group
+			if current == nil || inRules {
+				current = &group{}
+				robots.groups = append(robots.groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, agentToken(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			// An empty path matches nothing and is therefore ignored
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{
+				allow:   key == "allow",
+				pattern: normalizePattern(value),
+			})
+		case "crawl-delay":
+			// Crawl-delay is not part of RFC 9309 but is widely used, in (possibly fractional) seconds
+			// It does not end the group's user-agent lines, as it is not a rule
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		}
+	}
+
+	return robots
+}
+
+// agentToken returns the lowercased product token named by a user-agent line,
+// or "*" for the catch-all group
+func agentToken(value string) string {
+	if strings.HasPrefix(value, "*") {
+		return "*"
+	}
+
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return !isProductTokenChar(r)
+	})
+	if end >= 0 {
+		value = value[:end]
+	}
+	return strings.ToLower(value)
+}
+
+// groupFor returns the combination of every group naming the product token.
+// If no group names the token, the combination of the "*" groups applies.
+// The combined crawl delay is the largest one declared.
+func (r *robotsFile) groupFor(token string) *group {
+	token = strings.ToLower(token)
+
+	matched, fallback := &group{}, &group{}
+	found := false
+	for _, g := range r.groups {
+		if token != "" && g.names(token) {
+			matched.merge(g)
+			found = true
+		} else if g.names("*") {
+			fallback.merge(g)
+		}
+	}
+
+	if found {
+		return matched
+	}
+	return fallback
+}
+
+// merge adds the rules and crawl delay of another group
+func (g *group) merge(other *group) {
+	g.rules = append(g.rules, other.rules...)
+	g.crawlDelay = max(g.crawlDelay, other.crawlDelay)
+}
+
+// names reports whether the group lists the given agent token
+func (g *group) names(token string) bool {
+	for _, agent := range g.agents {
+		if agent == token {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed reports whether the crawler identified by the product token may
+// access the path. The most specific (longest) matching rule wins, and an allow
+// rule wins over an equally specific disallow rule.
+func (r *robotsFile) isAllowed(token, path string) bool {
+	path = normalizePath(path)
+
+	allowed := true
+	longest := -1
+	for _, rl := range r.groupFor(token).rules {
+		if !matchPattern(rl.pattern, path) {
+			continue
+		}
+		if length := len(rl.pattern); length > longest || (length == longest && rl.allow) {
+			longest = length
+			allowed = rl.allow
+		}
+	}
+
+	return allowed
+}
+
+// matchPattern reports whether the path matches a robots.txt pattern, where "*"
+// matches any sequence of characters and a trailing "$" anchors the end of the path
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	segments := strings.Split(pattern, "*")
+
+	// The first segment must be a prefix of the path
+	if !strings.HasPrefix(path, segments[0]) {
+		return false
+	}
+	pos := len(segments[0])
+
+	if len(segments) == 1 {
+		return !anchored || pos == len(path)
+	}
+
+	// Middle segments are matched at their earliest occurrence
+	for _, segment := range segments[1 : len(segments)-1] {
+		i := strings.Index(path[pos:], segment)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(segment)
+	}
+
+	last := segments[len(segments)-1]
+	if anchored {
+		return len(path)-pos >= len(last) && strings.HasSuffix(path, last)
+	}
+	return strings.Contains(path[pos:], last)
+}
+
+// normalizePattern normalizes the percent-encoding of a rule pattern, keeping
+// "*" wildcards and a trailing "$" anchor as special characters
+func normalizePattern(pattern string) string {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	segments := strings.Split(pattern, "*")
+	for i, segment := range segments {
+		segments[i] = normalizePath(segment)
+	}
+
+	normalized := strings.Join(segments, "*")
+	if anchored {
+		normalized += "$"
+	}
+	return normalized
+}
+
+// normalizePath normalizes percent-encoding so that equivalent paths compare
+// equal: unreserved characters are decoded, other escapes use uppercase hex, and
+// non-ASCII octets as well as the "*" and "$" special characters are encoded.
+func normalizePath(path string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(path))
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '%' && i+2 < len(path) && isHex(path[i+1]) && isHex(path[i+2]):
+			decoded := unhex(path[i+1])<<4 | unhex(path[i+2])
+			if isUnreserved(decoded) {
+				b.WriteByte(decoded)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(hexDigits[decoded>>4])
+				b.WriteByte(hexDigits[decoded&0x0f])
+			}
+			i += 2
+		case c >= 0x80 || c <= 0x20 || c == 0x7f || c == '*' || c == '$':
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// isUnreserved reports whether c is an unreserved character as defined by RFC 3986
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// unhex returns the value of a hexadecimal digit
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
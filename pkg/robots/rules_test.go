@@ -0,0 +1,195 @@
+package robots
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcExample is the example robots.txt from RFC 9309 section 5.1
+const rfcExample = `User-Agent: *
+Disallow: *.gif$
+Disallow: /example/
+Allow: /publications/
+
+User-Agent: foobot
+Disallow:/
+Allow:/example/page.html
+Allow:/example/allowed.gif
+
+User-Agent: barbot
+User-Agent: bazbot
+Disallow: /example/page.html
+
+User-Agent: quxbot
+
+EOF`
+
+func TestRFC9309Conformance(t *testing.T) {
+	tests := []struct {
+		name     string
+		robots   string
+		token    string
+		path     string
+		expected bool
+	}{
+		// Section 5.1: group selection
+		{"foobot allowed page", rfcExample, "foobot", "/example/page.html", true},
+		{"foobot allowed gif", rfcExample, "foobot", "/example/allowed.gif", true},
+		{"foobot disallowed everything else", rfcExample, "foobot", "/publications/", false},
+		{"foobot group matched case-insensitively", rfcExample, "FooBot", "/index.html", false},
+		{"barbot shares group", rfcExample, "barbot", "/example/page.html", false},
+		{"bazbot shares group", rfcExample, "bazbot", "/example/page.html", false},
+		{"bazbot other page allowed", rfcExample, "bazbot", "/example/other.html", true},
+		{"quxbot empty group allows all", rfcExample, "quxbot", "/example/page.html", true},
+		{"unknown bot uses catch-all gif rule", rfcExample, "otherbot", "/images/cat.gif", false},
+		{"unknown bot gif rule is end-anchored", rfcExample, "otherbot", "/images/cat.gif.html", true},
+		{"unknown bot uses catch-all prefix", rfcExample, "otherbot", "/example/page.html", false},
+		{"unknown bot allow beats shorter disallow", rfcExample, "otherbot", "/publications/cat.gif", true},
+		{"unknown bot unmatched path", rfcExample, "otherbot", "/index.html", true},
+
+		// Section 2.2.2: longest match
+		{
+			"longest match allow",
+			"User-Agent: foobot\nAllow: /example/page/\nDisallow: /example/page/disallowed.gif",
+			"foobot", "/example/page/allowed.html", true,
+		},
+		{
+			"longest match disallow",
+			"User-Agent: foobot\nAllow: /example/page/\nDisallow: /example/page/disallowed.gif",
+			"foobot", "/example/page/disallowed.gif", false,
+		},
+		{
+			"equivalent allow wins",
+			"User-Agent: *\nDisallow: /page\nAllow: /page",
+			"foobot", "/page", true,
+		},
+
+		// Section 2.2.2: percent-encoding equivalence
+		{"query is matched", "User-Agent: *\nDisallow: /foo/bar?baz=quz", "foobot", "/foo/bar?baz=quz", false},
+		{"non-ASCII pattern matches encoded path", "User-Agent: *\nDisallow: /foo/bar/ツ", "foobot", "/foo/bar/%E3%83%84", false},
+		{"encoded pattern matches encoded path", "User-Agent: *\nDisallow: /foo/bar/%E3%83%84", "foobot", "/foo/bar/%e3%83%84", false},
+		{"encoded unreserved is decoded", "User-Agent: *\nDisallow: /foo/bar/%62%61%7A", "foobot", "/foo/bar/baz", false},
+
+		// Section 2.2.3: special characters
+		{"wildcard in the middle", "User-Agent: *\nDisallow: /path/*/file", "foobot", "/path/a/b/file.html", false},
+		{"wildcard does not match missing suffix", "User-Agent: *\nDisallow: /path/*/file", "foobot", "/path/file", true},
+		{"end anchor", "User-Agent: *\nDisallow: /path$", "foobot", "/path/more", true},
+		{"end anchor exact", "User-Agent: *\nDisallow: /path$", "foobot", "/path", false},
+		{"encoded asterisk is literal", "User-Agent: *\nDisallow: /path/file-with-a-%2A.html", "foobot", "/path/file-with-a-*.html", false},
+		{"encoded dollar is literal", "User-Agent: *\nDisallow: /path/foo-%24", "foobot", "/path/foo-$", false},
+
+		// Parsing details
+		{"comments are ignored", "User-Agent: * # everyone\nDisallow: /secret # keep out", "foobot", "/secret", false},
+		{"rules before any group are ignored", "Disallow: /\nUser-Agent: *\nDisallow: /x", "foobot", "/y", true},
+		{"unknown records do not end group", "User-Agent: foobot\nCrawl-delay: 5\nDisallow: /x", "foobot", "/x", false},
+		{"duplicate groups are combined", "User-Agent: foobot\nDisallow: /a\n\nUser-Agent: *\nDisallow: /\n\nUser-Agent: foobot\nDisallow: /b", "foobot", "/b", false},
+		{"combined groups exclude catch-all", "User-Agent: foobot\nDisallow: /a\n\nUser-Agent: *\nDisallow: /\n\nUser-Agent: foobot\nDisallow: /b", "foobot", "/c", true},
+		{"empty disallow allows all", "User-Agent: *\nDisallow:", "foobot", "/anything", true},
+		{"CRLF line endings", "User-Agent: *\r\nDisallow: /private\r\n", "foobot", "/private/x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseRobots(tt.robots).isAllowed(tt.token, tt.path)
+			if result != tt.expected {
+				t.Errorf("expected %v for %s as %s, got %v", tt.expected, tt.path, tt.token, result)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish.asp", false},
+		{"/fish*", "/fishheads/yummy.html", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/*.php$", "/folder/filename.php", true},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"*", "/", true},
+		{"/a*b*c$", "/axbxc", true},
+		{"/a*b*c$", "/axbxcd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			result := matchPattern(normalizePattern(tt.pattern), normalizePath(tt.path))
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	robots := `User-agent: *
+Crawl-delay: 2
+Disallow: /tmp
+
+User-agent: foobot
+Crawl-delay: 0.5
+Disallow: /private
+
+User-agent: foobot
+Crawl-delay: 1.5
+Disallow: /drafts
+
+User-agent: barbot
+Crawl-delay: not-a-number`
+
+	tests := []struct {
+		token    string
+		expected time.Duration
+	}{
+		{"foobot", 1500 * time.Millisecond},
+		{"otherbot", 2 * time.Second},
+		{"barbot", 0},
+	}
+
+	parsed := parseRobots(robots)
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if delay := parsed.groupFor(tt.token).crawlDelay; delay != tt.expected {
+				t.Errorf("expected crawl delay %s, got %s", tt.expected, delay)
+			}
+		})
+	}
+
+	// Crawl-delay is not a rule, so the following user-agent line joins its group
+	parsed = parseRobots("User-agent: a\nCrawl-delay: 5\nUser-agent: b\nDisallow: /")
+	if len(parsed.groups) != 1 {
+		t.Fatalf("expected a single group, got %d", len(parsed.groups))
+	}
+	for _, token := range []string{"a", "b"} {
+		if parsed.isAllowed(token, "/page") || parsed.groupFor(token).crawlDelay != 5*time.Second {
+			t.Errorf("expected %s to be disallowed with a 5s crawl delay", token)
+		}
+	}
+}
+
+func TestSitemapLines(t *testing.T) {
+	robots := `Sitemap: https://example.com/sitemap.xml
+User-agent: *
+Disallow: /private
+sitemap:https://example.com/news.xml # news only
+Sitemap:
+
+User-agent: foobot
+Disallow: /`
+
+	parsed := parseRobots(robots)
+	if len(parsed.sitemaps) != 2 || parsed.sitemaps[0] != "https://example.com/sitemap.xml" ||
+		parsed.sitemaps[1] != "https://example.com/news.xml" {
+		t.Errorf("expected two sitemaps, got %v", parsed.sitemaps)
+	}
+	if parsed.isAllowed("otherbot", "/private") || parsed.isAllowed("foobot", "/public") {
+		t.Error("expected sitemap lines not to end or split groups")
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
+)
+
+// maxBatchSize caps the number of requests in one fetch_many call
+const maxBatchSize = 50
+
+// FetchManyParams defines the input parameters for the fetch_many tool
+type FetchManyParams struct {
+	URLs        []string       `json:"urls,omitempty" mcp:"URLs to fetch with the default options"`
+	Requests    []FetchParams  `json:"requests,omitempty" mcp:"Requests with their own fetch arguments, fetched after urls"`
+	Defaults    map[string]any `json:"defaults,omitempty" mcp:"fetch arguments applied to every request that does not set them"`
+	Concurrency *int           `json:"concurrency,omitempty" mcp:"Maximum number of URLs fetched at the same time"`
+	Timeout     *int           `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on the whole batch"`
+}
+
+// FetchManyOutput is the structured result of the fetch_many tool
+type FetchManyOutput struct {
+	Results   []FetchManyResult `json:"results" mcp:"Per-URL results, in request order"`
+	Succeeded int               `json:"succeeded" mcp:"Number of URLs fetched successfully"`
+	Failed    int               `json:"failed" mcp:"Number of URLs that failed"`
+}
+
+// FetchManyResult is the outcome of one request of a batch
+type FetchManyResult struct {
+	URL     string       `json:"url" mcp:"Requested URL"`
+	Status  int          `json:"status,omitempty" mcp:"HTTP status code, when a response was received"`
+	Error   string       `json:"error,omitempty" mcp:"Why the fetch failed"`
+	Content string       `json:"content,omitempty" mcp:"Fetched content, as the fetch tool returns it"`
+	Result  *FetchOutput `json:"result,omitempty" mcp:"Structured fetch result of a successful fetch"`
+}
+
+// handleFetchManyTool processes fetch_many tool requests
+func (fs *FetchServer) handleFetchManyTool(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[FetchManyParams],
+) (*mcp.CallToolResultFor[FetchManyOutput], error) {
+	log.Printf("Tool call received: fetch_many")
+
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	requests, err := batchRequests(params.Arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := fs.maxWorkers()
+	if params.Arguments.Concurrency != nil {
+		if *params.Arguments.Concurrency <= 0 {
+			return nil, fmt.Errorf("concurrency must be positive")
+		}
+		workers = min(*params.Arguments.Concurrency, workers)
+	}
+
+	output := FetchManyOutput{Results: fs.fetchAll(ctx, requests, workers)}
+	for _, result := range output.Results {
+		if result.Error == "" {
+			output.Succeeded++
+		} else {
+			output.Failed++
+		}
+	}
+	log.Printf("Batch of %d URLs completed: %d succeeded, %d failed", len(requests), output.Succeeded, output.Failed)
+
+	return &mcp.CallToolResultFor[FetchManyOutput]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: formatBatch(output)}},
+		StructuredContent: output,
+	}, nil
+}
+
+// maxWorkers returns the configured limit on parallel fetches of a batch
+func (fs *FetchServer) maxWorkers() int {
+	if fs.config.MaxWorkers > 0 {
+		return fs.config.MaxWorkers
+	}
+	return config.DefaultMaxWorkers
+}
+
+// fetchAll runs the requests through the fetch tool on up to workers
+// goroutines. Per-host politeness still applies, so requests to the same host
+// queue up behind each other.
+func (fs *FetchServer) fetchAll(ctx context.Context, requests []FetchParams, workers int) []FetchManyResult {
+	results := make([]FetchManyResult, len(requests))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fs.fetchOne(ctx, requests[i])
+			}
+		}()
+	}
+
+	for i := range requests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// fetchOne fetches a single request of a batch, turning failures into an error result
+func (fs *FetchServer) fetchOne(ctx context.Context, req FetchParams) FetchManyResult {
+	result := FetchManyResult{URL: req.URL}
+
+	fetched, err := fs.handleFetchTool(ctx, nil, &mcp.CallToolParamsFor[FetchParams]{Name: "fetch", Arguments: req})
+	if err != nil {
+		result.Error = err.Error()
+		var statusErr *fetcher.StatusError
+		if errors.As(err, &statusErr) {
+			result.Status = statusErr.StatusCode
+		}
+		return result
+	}
+
+	output := fetched.StructuredContent
+	result.Status = output.Status
+	result.Content = fetched.Content[0].(*mcp.TextContent).Text
+	result.Result = &output
+	return result
+}
+
+// batchRequests lists the requests of a batch, the bare URLs first, with the
+// default arguments filled in
+func batchRequests(params FetchManyParams) ([]FetchParams, error) {
+	requests := make([]FetchParams, 0, len(params.URLs)+len(params.Requests))
+	for _, u := range params.URLs {
+		requests = append(requests, FetchParams{URL: u})
+	}
+	requests = append(requests, params.Requests...)
+
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no urls or requests to fetch")
+	}
+	if len(requests) > maxBatchSize {
+		return nil, fmt.Errorf("too many requests: %d exceeds the limit of %d", len(requests), maxBatchSize)
+	}
+
+	if len(params.Defaults) > 0 {
+		for i := range requests {
+			merged, err := withDefaults(requests[i], params.Defaults)
+			if err != nil {
+				return nil, err
+			}
+			requests[i] = merged
+		}
+	}
+	return requests, nil
+}
+
+// withDefaults fills in the fetch arguments a request leaves unset from defaults
+func withDefaults(req FetchParams, defaults map[string]any) (FetchParams, error) {
+	fields := make(map[string]any, len(defaults))
+	for key, value := range defaults {
+		fields[key] = value
+	}
+
+	// The request's own arguments take precedence: those not omitted as empty,
+	// and those it gave explicitly even as false or zero
+	own, err := json.Marshal(req)
+	if err != nil {
+		return FetchParams{}, err
+	}
+	if err := json.Unmarshal(own, &fields); err != nil {
+		return FetchParams{}, err
+	}
+	for key, value := range req.given {
+		fields[key] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return FetchParams{}, fmt.Errorf("invalid defaults: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var merged FetchParams
+	if err := decoder.Decode(&merged); err != nil {
+		return FetchParams{}, fmt.Errorf("invalid defaults: %v", err)
+	}
+	return merged, nil
+}
+
+// UnmarshalJSON decodes fetch arguments, rejecting unknown ones, and records
+// which arguments were given
+func (p *FetchParams) UnmarshalJSON(data []byte) error {
+	type plain FetchParams
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &p.given)
+}
+
+// formatBatch renders the results of a batch as one markdown section per URL
+func formatBatch(output FetchManyOutput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Fetched %d of %d URLs", output.Succeeded, len(output.Results))
+
+	for _, result := range output.Results {
+		fmt.Fprintf(&b, "\n\n## %s", result.URL)
+		if result.Status != 0 {
+			fmt.Fprintf(&b, " (HTTP %d)", result.Status)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(&b, "\n\nError: %s", result.Error)
+		} else {
+			fmt.Fprintf(&b, "\n\n%s", result.Content)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+)
+
+func TestHandleFetchManyTool(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+		MaxWorkers:   2,
+	}
+
+	server := newTestServer(t, cfg)
+
+	var active, peak atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request) {
+		if n := active.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		defer active.Add(-1)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("content of " + r.URL.Path))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	maxLength := 10
+	params := &mcp.CallToolParamsFor[FetchManyParams]{
+		Name: "fetch_many",
+		Arguments: FetchManyParams{
+			URLs:     []string{testServer.URL + "/page/a", testServer.URL + "/missing", testServer.URL + "/page/b"},
+			Requests: []FetchParams{{URL: testServer.URL + "/page/c", MaxLength: &maxLength}},
+			Defaults: map[string]any{"max_length": 100},
+		},
+	}
+
+	result, err := server.handleFetchManyTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	output := result.StructuredContent
+	if output.Succeeded != 3 || output.Failed != 1 || len(output.Results) != 4 {
+		t.Fatalf("expected 3 successes and 1 failure, got %+v", output)
+	}
+	if missing := output.Results[1]; missing.Status != http.StatusNotFound || missing.Error == "" || missing.Result != nil {
+		t.Errorf("expected a 404 result, got %+v", missing)
+	}
+	if first := output.Results[0]; first.Content != "content of /page/a" || first.Result == nil || first.Result.Status != 200 {
+		t.Errorf("unexpected first result %+v", first)
+	}
+	if last := output.Results[3]; !strings.HasPrefix(last.Content, "content of\n\n[Content truncated.") {
+		t.Errorf("expected the request's own max_length to win over the default, got %q", last.Content)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent fetches, got %d", peak.Load())
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.HasPrefix(text, "Fetched 3 of 4 URLs") || !strings.Contains(text, "/missing (HTTP 404)\n\nError: HTTP 404") {
+		t.Errorf("unexpected text result:\n%s", text)
+	}
+}
+
+func TestBatchRequests(t *testing.T) {
+	if _, err := batchRequests(FetchManyParams{}); err == nil {
+		t.Error("expected an empty batch to be rejected")
+	}
+	if _, err := batchRequests(FetchManyParams{URLs: make([]string, maxBatchSize+1)}); err == nil {
+		t.Error("expected an oversized batch to be rejected")
+	}
+	if _, err := batchRequests(FetchManyParams{URLs: []string{"https://example.com"}, Defaults: map[string]any{"bogus": 1}}); err == nil {
+		t.Error("expected unknown default arguments to be rejected")
+	}
+
+	requests, err := batchRequests(FetchManyParams{
+		URLs:     []string{"https://example.com/a"},
+		Requests: []FetchParams{{URL: "https://example.com/b", Unit: "bytes"}},
+		Defaults: map[string]any{"unit": "tokens", "raw": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests[0].Unit != "tokens" || !requests[0].Raw || requests[1].Unit != "bytes" || !requests[1].Raw {
+		t.Errorf("expected defaults to fill in unset arguments only, got %+v", requests)
+	}
+}
+
+func TestBatchRequestsExplicitOverrides(t *testing.T) {
+	var params FetchManyParams
+	err := json.Unmarshal([]byte(`{
+		"urls": ["https://example.com/a"],
+		"requests": [{"url": "https://example.com/b", "raw": false, "start_index": 0, "boundary_aware": false}],
+		"defaults": {"raw": true, "start_index": 10, "boundary_aware": true}
+	}`), &params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests, err := batchRequests(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !requests[0].Raw || requests[0].StartIndex == nil || *requests[0].StartIndex != 10 || !requests[0].BoundaryAware {
+		t.Errorf("expected the bare URL to take the defaults, got %+v", requests[0])
+	}
+	if requests[1].Raw || requests[1].StartIndex == nil || *requests[1].StartIndex != 0 || requests[1].BoundaryAware {
+		t.Errorf("expected explicit false and zero arguments to override the defaults, got %+v", requests[1])
+	}
+
+	if err := json.Unmarshal([]byte(`{"requests": [{"url": "https://example.com/", "bogus": 1}]}`), &params); err == nil {
+		t.Error("expected unknown request arguments to be rejected")
+	}
+}
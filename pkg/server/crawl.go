@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// crawl tool limits
+const (
+	defaultCrawlDepth = 2
+	maxCrawlDepth     = 5
+	defaultCrawlPages = 20
+	maxCrawlPages     = 100
+)
+
+// Crawl result formats
+const (
+	// crawlFormatMarkdown concatenates the content of the pages under per-page headers
+	crawlFormatMarkdown = "markdown"
+	// crawlFormatIndex lists the pages without their content
+	crawlFormatIndex = "index"
+)
+
+// CrawlParams defines the input parameters for the crawl tool
+type CrawlParams struct {
+	URL        string `json:"url" mcp:"Seed URL the crawl starts from"`
+	Scope      string `json:"scope,omitempty" mcp:"Links to follow: path (default), under the seed's directory, or host"`
+	PathPrefix string `json:"path_prefix,omitempty" mcp:"Path prefix of the path scope, instead of the seed's directory"`
+	MaxDepth   *int   `json:"max_depth,omitempty" mcp:"Number of links to follow away from the seed (default: 2, max: 5)"`
+	MaxPages   *int   `json:"max_pages,omitempty" mcp:"Maximum number of pages to fetch (default: 20, max: 100)"`
+	Format     string `json:"format,omitempty" mcp:"Result format: markdown (default) with the pages' content, or index"`
+	MaxLength  *int   `json:"max_length,omitempty" mcp:"Maximum number of characters (or units) of the result to return"`
+	StartIndex *int   `json:"start_index,omitempty" mcp:"Start index in the result, to page through it"`
+	Unit       string `json:"unit,omitempty" mcp:"Unit for max_length and start_index: chars (default), bytes or tokens"`
+	Timeout    *int   `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on this call"`
+}
+
+// CrawlOutput is the structured result of the crawl tool
+type CrawlOutput struct {
+	Pages          []CrawledPage `json:"pages" mcp:"Pages in the order they were crawled"`
+	Unit           string        `json:"unit" mcp:"Unit in which lengths and indexes are measured"`
+	TotalLength    int           `json:"total_length" mcp:"Length of the whole result"`
+	ReturnedRange  Range         `json:"returned_range" mcp:"Range of the result that was returned"`
+	NextStartIndex *int          `json:"next_start_index,omitempty" mcp:"start_index of the next page, if the result was truncated"`
+}
+
+// CrawledPage describes a page visited by a crawl
+type CrawledPage struct {
+	URL      string `json:"url" mcp:"URL the page was reached by"`
+	FinalURL string `json:"final_url,omitempty" mcp:"URL the page was served from, after following redirects"`
+	Depth    int    `json:"depth" mcp:"Number of links followed from the seed"`
+	Status   int    `json:"status,omitempty" mcp:"HTTP status code"`
+	Title    string `json:"title,omitempty" mcp:"Article title"`
+	Error    string `json:"error,omitempty" mcp:"Why the page could not be fetched"`
+}
+
+// handleCrawlTool processes crawl tool requests
+func (fs *FetchServer) handleCrawlTool(
+	ctx context.Context,
+	session *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[CrawlParams],
+) (*mcp.CallToolResultFor[CrawlOutput], error) {
+	log.Printf("Tool call received: crawl")
+
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	args := params.Arguments
+	if args.Format != "" && args.Format != crawlFormatMarkdown && args.Format != crawlFormatIndex {
+		return nil, fmt.Errorf("invalid format %q: expected %s or %s", args.Format, crawlFormatMarkdown, crawlFormatIndex)
+	}
+	unit, err := processor.ParseUnit(args.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	crawlReq := &fetcher.CrawlRequest{
+		Seed:       args.URL,
+		Scope:      fetcher.CrawlScope(args.Scope),
+		PathPrefix: args.PathPrefix,
+		MaxDepth:   defaultCrawlDepth,
+		MaxPages:   defaultCrawlPages,
+		Page:       fetcher.FetchRequest{AbsoluteURLs: true},
+		Progress:   crawlProgress(ctx, session, params.GetProgressToken()),
+	}
+	if err := crawlLimits(args, crawlReq); err != nil {
+		return nil, err
+	}
+
+	pages, err := fs.fetcher.Crawl(ctx, crawlReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var text string
+	if args.Format == crawlFormatIndex {
+		text = formatCrawlIndex(args.URL, pages)
+	} else {
+		text = formatCrawlMarkdown(pages)
+	}
+	page := fs.fetcher.Paginate(text, &fetcher.FetchRequest{MaxLength: args.MaxLength, StartIndex: args.StartIndex, Unit: unit})
+
+	output := CrawlOutput{
+		Pages:         crawledPages(pages),
+		Unit:          string(page.Unit),
+		TotalLength:   page.Total,
+		ReturnedRange: Range{Start: page.Start, End: page.End},
+	}
+	if page.Truncated {
+		next := page.End
+		output.NextStartIndex = &next
+	}
+
+	return &mcp.CallToolResultFor[CrawlOutput]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: page.Content}},
+		StructuredContent: output,
+	}, nil
+}
+
+// crawlLimits applies the depth and page limits of the arguments to a crawl request
+func crawlLimits(args CrawlParams, req *fetcher.CrawlRequest) error {
+	if args.MaxDepth != nil {
+		if *args.MaxDepth < 0 {
+			return fmt.Errorf("max_depth must not be negative")
+		}
+		req.MaxDepth = min(*args.MaxDepth, maxCrawlDepth)
+	}
+	if args.MaxPages != nil {
+		if *args.MaxPages <= 0 {
+			return fmt.Errorf("max_pages must be positive")
+		}
+		req.MaxPages = min(*args.MaxPages, maxCrawlPages)
+	}
+	return nil
+}
+
+// crawlProgress returns a callback reporting crawl progress to the client as
+// progress notifications, or nil if the client asked for none
+func crawlProgress(ctx context.Context, session *mcp.ServerSession, token any) func(*fetcher.CrawlPage, int, int) {
+	if session == nil || token == nil {
+		return nil
+	}
+	return func(page *fetcher.CrawlPage, done, total int) {
+		err := session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(done),
+			Total:         float64(total),
+			Message:       fmt.Sprintf("Crawled %s", page.URL),
+		})
+		if err != nil {
+			log.Printf("Failed to send crawl progress: %v", err)
+		}
+	}
+}
+
+// crawledPages describes the pages of a crawl for the structured result
+func crawledPages(pages []*fetcher.CrawlPage) []CrawledPage {
+	described := make([]CrawledPage, 0, len(pages))
+	for _, page := range pages {
+		d := CrawledPage{URL: page.URL, Depth: page.Depth}
+		if page.Err != nil {
+			d.Error = page.Err.Error()
+		} else {
+			d.FinalURL = page.Result.FinalURL
+			d.Status = page.Result.Status
+			d.Title = page.Result.Title
+		}
+		described = append(described, d)
+	}
+	return described
+}
+
+// formatCrawlMarkdown concatenates the content of crawled pages, each under a
+// header naming its title and URL
+func formatCrawlMarkdown(pages []*fetcher.CrawlPage) string {
+	sections := make([]string, 0, len(pages))
+	for _, page := range pages {
+		if page.Err != nil {
+			sections = append(sections, fmt.Sprintf("# %s\n\nError: %v", page.URL, page.Err))
+			continue
+		}
+		title := page.Result.Title
+		if title == "" {
+			title = page.Result.FinalURL
+		}
+		sections = append(sections, fmt.Sprintf("# %s\n\nSource: %s (depth %d)\n\n%s",
+			title, page.Result.FinalURL, page.Depth, page.Result.Content))
+	}
+	return strings.Join(sections, "\n\n---\n\n")
+}
+
+// formatCrawlIndex lists crawled pages as a numbered markdown list
+func formatCrawlIndex(seed string, pages []*fetcher.CrawlPage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Crawled %d pages from %s\n", len(pages), seed)
+
+	for i, page := range pages {
+		if page.Err != nil {
+			fmt.Fprintf(&b, "\n%d. %s · depth %d · error: %v", i+1, page.URL, page.Depth, page.Err)
+			continue
+		}
+		title := page.Result.Title
+		if title == "" {
+			title = page.Result.FinalURL
+		}
+		fmt.Fprintf(&b, "\n%d. [%s](%s) · depth %d · HTTP %d", i+1, title, page.Result.FinalURL, page.Depth, page.Result.Status)
+	}
+
+	return b.String()
+}
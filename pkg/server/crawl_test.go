@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+)
+
+// createDocsServer serves a guide of two pages next to an unrelated blog
+func createDocsServer() *httptest.Server {
+	pages := map[string]string{
+		"/guide/":      `<title>Guide</title><a href="setup">Setup</a> <a href="/blog/">Blog</a> <a href="/missing/">Gone</a>`,
+		"/guide/setup": `<title>Setup</title><p>Set the proxy.</p><a href="/guide/missing">Gone</a>`,
+		"/blog/":       `<title>Blog</title><p>Posts</p>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head>" + body + "</head></html>"))
+	}))
+}
+
+func TestHandleCrawlTool(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := createDocsServer()
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[CrawlParams]{
+		Name:      "crawl",
+		Arguments: CrawlParams{URL: testServer.URL + "/guide/"},
+	}
+
+	result, err := server.handleCrawlTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	pages := result.StructuredContent.Pages
+	if len(pages) != 3 || pages[1].Title != "Setup" || pages[1].Depth != 1 || pages[2].Error == "" {
+		t.Fatalf("expected the guide, its setup page and a failed link, got %+v", pages)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	for _, want := range []string{"# Guide\n\nSource: " + testServer.URL + "/guide/ (depth 0)", "Set the proxy.", "Error: HTTP 404"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Posts") {
+		t.Error("expected the blog to be outside the path scope")
+	}
+
+	maxLength := 20
+	params.Arguments.Format = crawlFormatIndex
+	params.Arguments.MaxLength = &maxLength
+	result, err = server.handleCrawlTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if output := result.StructuredContent; output.NextStartIndex == nil || *output.NextStartIndex != 20 {
+		t.Errorf("expected the index to be paged, got %+v", output)
+	}
+
+	params.Arguments.Format = "html"
+	if _, err := server.handleCrawlTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an invalid format to be rejected")
+	}
+}
+
+func TestFormatCrawlIndex(t *testing.T) {
+	testServer := createDocsServer()
+	defer testServer.Close()
+
+	server := newTestServer(t, config.Config{UserAgent: "test-agent", AllowedCIDRs: []string{"127.0.0.0/8"}})
+	maxDepth := 0
+	result, err := server.handleCrawlTool(context.Background(), nil, &mcp.CallToolParamsFor[CrawlParams]{
+		Arguments: CrawlParams{URL: testServer.URL + "/guide/", MaxDepth: &maxDepth, Format: crawlFormatIndex},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "Crawled 1 pages from " + testServer.URL + "/guide/\n\n" +
+		"1. [Guide](" + testServer.URL + "/guide/) · depth 0 · HTTP 200"
+	if text := result.Content[0].(*mcp.TextContent).Text; text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestCrawlProgressNotifications(t *testing.T) {
+	testServer := createDocsServer()
+	defer testServer.Close()
+
+	server := newTestServer(t, config.Config{UserAgent: "test-agent", AllowedCIDRs: []string{"127.0.0.0/8"}})
+
+	progress := make(chan *mcp.ProgressNotificationParams, 10)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, _ *mcp.ClientSession, params *mcp.ProgressNotificationParams) {
+			progress <- params
+		},
+	})
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.mcpServer.Connect(ctx, serverTransport)
+	if err != nil {
+		t.Fatalf("failed to connect the server: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := client.Connect(ctx, clientTransport)
+	if err != nil {
+		t.Fatalf("failed to connect the client: %v", err)
+	}
+	defer session.Close()
+
+	// SetProgressToken does not store the token in a nil Meta, so set it directly
+	params := &mcp.CallToolParams{
+		Meta:      mcp.Meta{"progressToken": "crawl-1"},
+		Name:      "crawl",
+		Arguments: map[string]any{"url": testServer.URL + "/guide/", "format": "index"},
+	}
+	result, err := session.CallTool(ctx, params)
+	if err != nil || result.IsError {
+		t.Fatalf("crawl failed: %v %+v", err, result)
+	}
+
+	// Notifications are delivered asynchronously, possibly after the result and out of order
+	var last *mcp.ProgressNotificationParams
+	for i := 0; i < 3; i++ {
+		select {
+		case params := <-progress:
+			if last == nil || params.Progress > last.Progress {
+				last = params
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a notification per page, got %d", i)
+		}
+	}
+	if last.ProgressToken != "crawl-1" || last.Progress != 3 || last.Total != 3 || !strings.HasPrefix(last.Message, "Crawled ") {
+		t.Errorf("unexpected final notification %+v", last)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// ExtractLinksParams defines the input parameters for the extract_links tool
+type ExtractLinksParams struct {
+	URL      string `json:"url" mcp:"URL of the page to list the links of"`
+	Pattern  string `json:"pattern,omitempty" mcp:"Regular expression the absolute link URLs must match"`
+	SameHost bool   `json:"same_host,omitempty" mcp:"Only list links to the page's own host"`
+	Timeout  *int   `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on this call"`
+}
+
+// ExtractLinksOutput is the structured result of the extract_links tool
+type ExtractLinksOutput struct {
+	FinalURL  string           `json:"final_url" mcp:"URL the page was served from, after following redirects"`
+	FetchedAt string           `json:"fetched_at" mcp:"Time the response was received in RFC 3339 format"`
+	Links     []processor.Link `json:"links" mcp:"Links in document order"`
+}
+
+// handleExtractLinksTool processes extract_links tool requests
+func (fs *FetchServer) handleExtractLinksTool(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[ExtractLinksParams],
+) (*mcp.CallToolResultFor[ExtractLinksOutput], error) {
+	log.Printf("Tool call received: extract_links")
+
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	opts := processor.LinkOptions{SameHost: params.Arguments.SameHost}
+	if params.Arguments.Pattern != "" {
+		if opts.Pattern, err = regexp.Compile(params.Arguments.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+
+	result, err := fs.fetcher.FetchLinks(ctx, params.Arguments.URL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResultFor[ExtractLinksOutput]{
+		Meta: mcp.Meta{
+			"politeness_delay_ms": result.Delay.Milliseconds(),
+			"body_truncated":      result.Truncated,
+		},
+		Content: []mcp.Content{&mcp.TextContent{Text: formatLinks(result.FinalURL, result.Links)}},
+		StructuredContent: ExtractLinksOutput{
+			FinalURL:  result.FinalURL,
+			FetchedAt: result.FetchedAt.UTC().Format(time.RFC3339),
+			Links:     result.Links,
+		},
+	}, nil
+}
+
+// formatLinks renders links as a markdown list, one link per item followed by
+// its classification and context
+func formatLinks(pageURL string, links []processor.Link) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d links on %s", len(links), pageURL)
+
+	for _, link := range links {
+		scope := "external"
+		if link.Internal {
+			scope = "internal"
+		}
+		text := link.Text
+		if text == "" {
+			text = link.URL
+		}
+		fmt.Fprintf(&b, "\n- [%s](%s) · %s · %s · %s", text, link.URL, scope, link.Section, link.FileType)
+		if link.Context != "" {
+			fmt.Fprintf(&b, "\n  > %s", link.Context)
+		}
+	}
+
+	return b.String()
+}
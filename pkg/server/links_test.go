@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+func TestHandleExtractLinksTool(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><nav><a href="/about">About</a></nav>
+<p>See the <a href="/docs#intro">docs</a> or <a href="https://example.org/">Example</a>.</p></body></html>`))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[ExtractLinksParams]{
+		Name:      "extract_links",
+		Arguments: ExtractLinksParams{URL: testServer.URL + "/page"},
+	}
+
+	result, err := server.handleExtractLinksTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	links := result.StructuredContent.Links
+	if len(links) != 3 || links[1].URL != testServer.URL+"/docs" || links[2].Internal {
+		t.Errorf("unexpected links %+v", links)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	for _, want := range []string{
+		"Found 3 links on " + testServer.URL + "/page",
+		"- [About](" + testServer.URL + "/about) · internal · nav · html",
+		"  > See the docs or Example.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	params.Arguments.SameHost = true
+	params.Arguments.Pattern = "/doc"
+	result, err = server.handleExtractLinksTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if links := result.StructuredContent.Links; len(links) != 1 || links[0].Text != "docs" {
+		t.Errorf("expected only the docs link, got %+v", links)
+	}
+
+	params.Arguments.Pattern = "("
+	if _, err := server.handleExtractLinksTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
+
+func TestFormatLinks(t *testing.T) {
+	text := formatLinks("https://example.com/", []processor.Link{
+		{URL: "https://example.com/a.pdf", Internal: true, Section: processor.SectionContent, FileType: "pdf"},
+	})
+
+	expected := "Found 1 links on https://example.com/\n" +
+		"- [https://example.com/a.pdf](https://example.com/a.pdf) · internal · content · pdf"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
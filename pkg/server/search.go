@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// search_page settings
+const (
+	// defaultSearchContext is the number of context lines shown around matches
+	defaultSearchContext = 2
+	// defaultMaxMatches is the number of matches returned by default
+	defaultMaxMatches = 20
+	// maxSearchContext caps the context lines a client may request
+	maxSearchContext = 50
+)
+
+// SearchPageParams defines the input parameters for the search_page tool
+type SearchPageParams struct {
+	URL           string `json:"url" mcp:"URL of the page to search"`
+	Query         string `json:"query" mcp:"Text to find, or a regular expression when regex is set"`
+	Regex         bool   `json:"regex,omitempty" mcp:"Treat the query as an RE2 regular expression"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty" mcp:"Match letter case exactly"`
+	ContextLines  *int   `json:"context_lines,omitempty" mcp:"Lines of context shown around each match (default: 2)"`
+	MaxMatches    *int   `json:"max_matches,omitempty" mcp:"Maximum number of matches to return (default: 20)"`
+	Unit          string `json:"unit,omitempty" mcp:"Unit of the returned offsets: chars (default), bytes or tokens"`
+	Refresh       bool   `json:"refresh,omitempty" mcp:"Revalidate the page with the server instead of reusing a fresh cached copy"`
+	Timeout       *int   `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on this call"`
+}
+
+// SearchPageOutput is the structured result of the search_page tool
+type SearchPageOutput struct {
+	FinalURL     string            `json:"final_url" mcp:"URL the page was served from, after following redirects"`
+	FetchedAt    string            `json:"fetched_at" mcp:"Time the response was received in RFC 3339 format"`
+	CacheStatus  string            `json:"cache_status" mcp:"Where the page came from: miss (network), hit (cache) or revalidated"`
+	Unit         string            `json:"unit" mcp:"Unit in which offsets are measured"`
+	TotalMatches int               `json:"total_matches" mcp:"Number of matching lines, including those not returned"`
+	Matches      []processor.Match `json:"matches" mcp:"Matches in document order with their context and offsets"`
+}
+
+// handleSearchPageTool processes search_page tool requests
+func (fs *FetchServer) handleSearchPageTool(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[SearchPageParams],
+) (*mcp.CallToolResultFor[SearchPageOutput], error) {
+	log.Printf("Tool call received: search_page")
+
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	args := params.Arguments
+	pattern, err := processor.SearchPattern(args.Query, args.Regex, args.CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := processor.ParseUnit(args.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := processor.SearchOptions{Context: defaultSearchContext, MaxMatches: defaultMaxMatches, Unit: unit}
+	if args.ContextLines != nil {
+		if *args.ContextLines < 0 {
+			return nil, fmt.Errorf("context_lines must not be negative")
+		}
+		opts.Context = min(*args.ContextLines, maxSearchContext)
+	}
+	if args.MaxMatches != nil {
+		if *args.MaxMatches <= 0 {
+			return nil, fmt.Errorf("max_matches must be positive")
+		}
+		opts.MaxMatches = *args.MaxMatches
+	}
+
+	// Repeated searches are served by the fetcher's HTTP and processed content caches
+	req := &fetcher.FetchRequest{URL: args.URL, AbsoluteURLs: true}
+	if args.Refresh {
+		req.Cache = fetcher.CacheNoCache
+	}
+	doc, err := fs.fetcher.FetchDocument(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, total := processor.Search(doc.Content, pattern, opts)
+	log.Printf("Found %d matches for %q in %s", total, args.Query, doc.FinalURL)
+
+	return &mcp.CallToolResultFor[SearchPageOutput]{
+		Meta: mcp.Meta{
+			"politeness_delay_ms": doc.Delay.Milliseconds(),
+			"body_truncated":      doc.Truncated,
+		},
+		Content: []mcp.Content{&mcp.TextContent{Text: formatMatches(args.Query, doc.FinalURL, matches, total)}},
+		StructuredContent: SearchPageOutput{
+			FinalURL:     doc.FinalURL,
+			FetchedAt:    doc.FetchedAt.UTC().Format(time.RFC3339),
+			CacheStatus:  doc.CacheStatus,
+			Unit:         string(unit),
+			TotalMatches: total,
+			Matches:      matches,
+		},
+	}, nil
+}
+
+// formatMatches renders search matches as snippets headed by their line and start index
+func formatMatches(query, pageURL string, matches []processor.Match, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d matches for %q on %s", total, query, pageURL)
+	if len(matches) < total {
+		fmt.Fprintf(&b, ", showing the first %d", len(matches))
+	}
+
+	for _, match := range matches {
+		fmt.Fprintf(&b, "\n\n--- Line %d, start_index %d ---\n%s", match.Line, match.StartIndex, match.Snippet)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+func TestHandleSearchPageTool(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:        "test-agent",
+		Transport:        config.TransportSSE,
+		AllowedCIDRs:     []string{"127.0.0.0/8"},
+		HTTPCacheSize:    1024 * 1024,
+		ContentCacheSize: 1024 * 1024,
+	}
+
+	server := newTestServer(t, cfg)
+
+	var requests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=300")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("intro\nset api_key here\nmiddle\nAPI_KEY again\noutro\n"))
+	}))
+	defer testServer.Close()
+
+	contextLines := 1
+	params := &mcp.CallToolParamsFor[SearchPageParams]{
+		Name:      "search_page",
+		Arguments: SearchPageParams{URL: testServer.URL, Query: "api_key", ContextLines: &contextLines},
+	}
+
+	result, err := server.handleSearchPageTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	output := result.StructuredContent
+	if output.TotalMatches != 2 || output.CacheStatus != fetcher.CacheStatusMiss || output.Matches[0].Snippet != "intro\nset api_key here\nmiddle" {
+		t.Errorf("unexpected output %+v", output)
+	}
+	if output.Matches[1].StartIndex != len("intro\nset api_key here\n") {
+		t.Errorf("expected the second snippet to start on the line before the match, got %d", output.Matches[1].StartIndex)
+	}
+
+	// A second search reuses the cached page
+	params.Arguments.Query = `api_key\s+here`
+	params.Arguments.Regex = true
+	params.Arguments.CaseSensitive = true
+	result, err = server.handleSearchPageTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.StructuredContent.CacheStatus != fetcher.CacheStatusHit || result.StructuredContent.TotalMatches != 1 ||
+		requests.Load() != 1 {
+		t.Errorf("expected one match in the cached page, got %+v after %d requests", result.StructuredContent, requests.Load())
+	}
+
+	params.Arguments.Refresh = true
+	if _, err := server.handleSearchPageTool(context.Background(), nil, params); err != nil || requests.Load() != 2 {
+		t.Errorf("expected refresh to fetch the page again, got %v after %d requests", err, requests.Load())
+	}
+
+	params.Arguments.Query = ""
+	if _, err := server.handleSearchPageTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an empty query to be rejected")
+	}
+}
+
+func TestFormatMatches(t *testing.T) {
+	text := formatMatches("key", "https://example.com/", []processor.Match{
+		{Line: 3, StartIndex: 10, Snippet: "the key"},
+	}, 4)
+
+	expected := "Found 4 matches for \"key\" on https://example.com/, showing the first 1\n\n" +
+		"--- Line 3, start_index 10 ---\nthe key"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
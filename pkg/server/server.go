@@ -3,28 +3,96 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/cache"
 	"github.com/stackloklabs/gofetch/pkg/config"
 	"github.com/stackloklabs/gofetch/pkg/fetcher"
+	"github.com/stackloklabs/gofetch/pkg/netguard"
 	"github.com/stackloklabs/gofetch/pkg/processor"
 	"github.com/stackloklabs/gofetch/pkg/robots"
+	"golang.org/x/net/http/httpproxy"
 )
 
 // FetchParams defines the input parameters for the fetch tool
 type FetchParams struct {
-	URL        string `json:"url" mcp:"URL to fetch"`
-	MaxLength  *int   `json:"max_length,omitempty" mcp:"Maximum number of characters to return"`
-	StartIndex *int   `json:"start_index,omitempty" mcp:"Start index for truncated content"`
-	Raw        bool   `json:"raw,omitempty" mcp:"Get the actual HTML content without simplification"`
+	URL           string `json:"url" mcp:"URL to fetch"`
+	MaxLength     *int   `json:"max_length,omitempty" mcp:"Maximum number of characters (or units) to return"`
+	StartIndex    *int   `json:"start_index,omitempty" mcp:"Start index for truncated content"`
+	Unit          string `json:"unit,omitempty" mcp:"Unit for max_length and start_index: chars (default), bytes or tokens"`
+	Raw           bool   `json:"raw,omitempty" mcp:"Get the actual HTML content without simplification"`
+	BoundaryAware bool   `json:"boundary_aware,omitempty" mcp:"Truncate at paragraph, heading or list-item boundaries"`
+	Pages         string `json:"pages,omitempty" mcp:"Pages of a PDF document to extract, e.g. 1-3,5,8-"`
+	JSONPath      string `json:"json_path,omitempty" mcp:"JSONPath expression selecting part of a JSON response, e.g. $.items[0]"`
+	JSONShape     bool   `json:"json_shape,omitempty" mcp:"Summarize the keys, types and array lengths of a JSON response"`
+	Since         string `json:"since,omitempty" mcp:"Only list feed entries published since this RFC 3339 time or YYYY-MM-DD date"`
+	Limit         int    `json:"limit,omitempty" mcp:"Maximum number of feed entries to list"`
+	DiscoverFeed  bool   `json:"discover_feed,omitempty" mcp:"Fetch the feed an HTML page links to instead of the page"`
+	Metadata      bool   `json:"metadata,omitempty" mcp:"Prefix the content with a header of the page's metadata"`
+	AbsoluteURLs  *bool  `json:"absolute_urls,omitempty" mcp:"Rewrite relative link and image URLs to absolute ones (default: true)"`
+	Timeout       *int   `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on this call"`
+
+	// Targeted extraction, converting only the matching elements of HTML pages
+	Selector         string   `json:"selector,omitempty" mcp:"CSS selector of the elements to convert instead of the article"`
+	XPath            string   `json:"xpath,omitempty" mcp:"XPath expression of the elements to convert, instead of selector"`
+	ExcludeSelectors []string `json:"exclude_selectors,omitempty" mcp:"CSS selectors of elements to remove from the selection"`
+
+	// Navigation of long documents by their headings
+	Section string `json:"section,omitempty" mcp:"Only return the content under this heading path, e.g. Setup > Proxy, or #anchor"`
+	Outline bool   `json:"outline,omitempty" mcp:"Return the heading outline with anchors and offsets instead of the content"`
+
+	// Use of the HTTP response cache
+	Cache string `json:"cache,omitempty" mcp:"HTTP cache mode: default, no-cache, only-if-cached or force-cache"`
+
+	// given holds the arguments present in the JSON the parameters were decoded
+	// from, so that explicit false and zero values override batch defaults
+	given map[string]json.RawMessage
 }
 
+// FetchOutput is the structured result of the fetch tool
+type FetchOutput struct {
+	FinalURL       string `json:"final_url" mcp:"URL the content was served from, after following redirects"`
+	Status         int    `json:"status" mcp:"HTTP status code"`
+	ContentType    string `json:"content_type" mcp:"Content-Type of the response"`
+	Charset        string `json:"charset,omitempty" mcp:"Character encoding the body was decoded from"`
+	Title          string `json:"title,omitempty" mcp:"Article title"`
+	Byline         string `json:"byline,omitempty" mcp:"Article author"`
+	Published      string `json:"published,omitempty" mcp:"Article publication time in RFC 3339 format"`
+	PageCount      int    `json:"page_count,omitempty" mcp:"Number of pages of a PDF document"`
+	Unit           string `json:"unit" mcp:"Unit in which lengths and indexes are measured"`
+	TotalLength    int    `json:"total_length" mcp:"Length of the whole processed content"`
+	ReturnedRange  Range  `json:"returned_range" mcp:"Range of the processed content that was returned"`
+	NextStartIndex *int   `json:"next_start_index,omitempty" mcp:"start_index of the next page, if the content was truncated"`
+	FetchedAt      string `json:"fetched_at" mcp:"Time the response was received in RFC 3339 format"`
+	FeedURL        string `json:"feed_url,omitempty" mcp:"Feed discovered on the requested page, if feed discovery was asked for"`
+	CacheStatus    string `json:"cache_status" mcp:"Where the response came from: miss (network), hit (cache) or revalidated"`
+
+	// Metadata is only set for HTML pages
+	Metadata *processor.Metadata `json:"metadata,omitempty" mcp:"OpenGraph, Twitter card, JSON-LD and microdata metadata"`
+
+	// Outline is only set when an outline was asked for
+	Outline []processor.Heading `json:"outline,omitempty" mcp:"Headings of the content with their anchors, offsets and lengths"`
+}
+
+// Range is a half-open range of content offsets
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// maxCallTimeout caps the per-call deadline a client may request
+const maxCallTimeout = 5 * time.Minute
+
 // FetchServer represents the MCP server for fetching web content
 type FetchServer struct {
 	config    config.Config
@@ -33,25 +101,36 @@ type FetchServer struct {
 }
 
 // NewFetchServer creates a new fetch server instance
-func NewFetchServer(cfg config.Config) *FetchServer {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+func NewFetchServer(cfg config.Config) (*FetchServer, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// Configure proxy if provided
-	if cfg.ProxyURL != "" {
-		if proxyURLParsed, err := url.Parse(cfg.ProxyURL); err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURLParsed),
-			}
-		}
+	// Create HTTP client with timeout
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
 	}
 
 	// Create components
-	robotsChecker := robots.NewChecker(cfg.UserAgent, cfg.IgnoreRobots, client)
+	var robotsOpts []robots.Option
+	if cfg.RobotsCacheTTL > 0 {
+		robotsOpts = append(robotsOpts, robots.WithCacheTTL(cfg.RobotsCacheTTL))
+	}
+	robotsChecker := robots.NewChecker(cfg.UserAgent, cfg.IgnoreRobots, client, robotsOpts...)
 	contentProcessor := processor.NewContentProcessor()
-	httpFetcher := fetcher.NewHTTPFetcher(client, robotsChecker, contentProcessor, cfg.UserAgent)
+	fetcherOpts := []fetcher.Option{
+		fetcher.WithMinHostInterval(cfg.MinHostInterval),
+		fetcher.WithMaxBodySize(cfg.MaxBodySize, oversizePolicy(cfg.OversizePolicy)),
+	}
+	if cfg.ContentCacheSize > 0 {
+		fetcherOpts = append(fetcherOpts, fetcher.WithContentCache(cfg.ContentCacheSize))
+	}
+	if store := newHTTPCache(cfg); store != nil {
+		fetcherOpts = append(fetcherOpts, fetcher.WithHTTPCache(store))
+	}
+	httpFetcher := fetcher.NewHTTPFetcher(client, robotsChecker, contentProcessor, cfg.UserAgent, fetcherOpts...)
 
 	fs := &FetchServer{
 		config:  cfg,
@@ -72,7 +151,117 @@ func NewFetchServer(cfg config.Config) *FetchServer {
 	// Setup tools
 	fs.setupTools()
 
-	return fs
+	return fs, nil
+}
+
+// newTransport builds the outbound HTTP transport, guarding every connection
+// against private, loopback and link-local destinations. Requests go through
+// the --proxy-url proxy, or otherwise the one named by HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY, only once their destination has passed the same check.
+func newTransport(cfg config.Config) (*http.Transport, error) {
+	allowed, err := netguard.ParseCIDRs(cfg.AllowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed CIDRs: %w", err)
+	}
+	guard := netguard.NewGuard(allowed)
+
+	selectProxy := httpproxy.FromEnvironment().ProxyFunc()
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		selectProxy = func(*url.URL) (*url.URL, error) {
+			return proxyURL, nil
+		}
+		log.Printf("Proxying through %s: destinations are checked before proxying, "+
+			"but the proxy resolves names again and must filter internal addresses itself", proxyURL.Redacted())
+	}
+
+	// Proxies are configured by the operator and trusted even when they live on
+	// an internal network, so the addresses of those in use bypass the guard
+	var proxies sync.Map
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := selectProxy(req.URL)
+		if err != nil || proxyURL == nil {
+			return nil, err
+		}
+		// The dial-time guard only sees the proxy, so check the destination
+		// before handing the request over
+		if err := guard.CheckHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		proxies.Store(proxyAddress(proxyURL), true)
+		return proxyURL, nil
+	}
+
+	directDialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := proxies.Load(addr); ok {
+			return directDialer.DialContext(ctx, network, addr)
+		}
+		return guard.DialContext(ctx, network, addr)
+	}
+
+	return transport, nil
+}
+
+// newHTTPCache builds the HTTP response cache from the configuration: in
+// memory, on disk or both. It returns nil when caching is disabled.
+func newHTTPCache(cfg config.Config) cache.Store {
+	var memory, disk cache.Store
+	if cfg.HTTPCacheSize > 0 {
+		memory = cache.NewMemoryStore(cfg.HTTPCacheSize)
+	}
+	if cfg.HTTPCacheDir != "" {
+		store, err := cache.NewDiskStore(cfg.HTTPCacheDir, cfg.HTTPCacheDiskSize)
+		if err != nil {
+			log.Printf("Disabling the on-disk HTTP cache: %v", err)
+		} else {
+			disk = store
+		}
+	}
+
+	switch {
+	case memory != nil && disk != nil:
+		return cache.NewTieredStore(memory, disk)
+	case memory != nil:
+		return memory
+	case disk != nil:
+		return disk
+	default:
+		return nil
+	}
+}
+
+// oversizePolicy maps the configured oversize policy onto the fetcher's, defaulting to an error
+func oversizePolicy(policy string) fetcher.OversizePolicy {
+	switch policy {
+	case config.OversizeTruncate:
+		return fetcher.OversizeTruncate
+	case config.OversizeError, "":
+		return fetcher.OversizeError
+	default:
+		log.Printf("Unknown oversize policy %q, using %q", policy, config.OversizeError)
+		return fetcher.OversizeError
+	}
+}
+
+// proxyAddress returns the host:port the transport dials to reach the proxy
+func proxyAddress(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+
+	port := "80"
+	switch proxyURL.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
 }
 
 // handleInitialized sends an endpoint event to the client after initialization
@@ -106,7 +295,7 @@ func (fs *FetchServer) handleInitialized(ctx context.Context, session *mcp.Serve
 	}
 }
 
-// setupTools registers the fetch tool with the MCP server
+// setupTools registers the fetch, fetch_many, crawl, extract_links, search_page and sitemap tools with the MCP server
 func (fs *FetchServer) setupTools() {
 	fetchTool := &mcp.Tool{
 		Name:        "fetch",
@@ -114,20 +303,130 @@ func (fs *FetchServer) setupTools() {
 	}
 
 	mcp.AddTool(fs.mcpServer, fetchTool, fs.handleFetchTool)
+
+	batchTool := &mcp.Tool{
+		Name: "fetch_many",
+		Description: "Fetches several URLs in parallel, each with shared or its own fetch arguments, and returns " +
+			"a result per URL so that one failure does not fail the batch.",
+	}
+
+	mcp.AddTool(fs.mcpServer, batchTool, fs.handleFetchManyTool)
+
+	crawlTool := &mcp.Tool{
+		Name: "crawl",
+		Description: "Crawls a site section from a seed URL, following links within a path or host scope up to a depth " +
+			"and page limit, and returns the pages' markdown under per-page headers or an index of the pages.",
+	}
+
+	mcp.AddTool(fs.mcpServer, crawlTool, fs.handleCrawlTool)
+
+	linksTool := &mcp.Tool{
+		Name: "extract_links",
+		Description: "Lists the links on a web page as absolute, deduplicated URLs with their anchor text and context, " +
+			"classified as internal or external, by page section and by file type.",
+	}
+
+	mcp.AddTool(fs.mcpServer, linksTool, fs.handleExtractLinksTool)
+
+	searchTool := &mcp.Tool{
+		Name: "search_page",
+		Description: "Searches the processed markdown of a web page for text or a regular expression and returns " +
+			"the matching snippets with surrounding lines and the start_index to read on from with fetch.",
+	}
+
+	mcp.AddTool(fs.mcpServer, searchTool, fs.handleSearchPageTool)
+
+	sitemapTool := &mcp.Tool{
+		Name: "sitemap",
+		Description: "Lists the URLs of a site from its sitemaps, discovered from robots.txt or /sitemap.xml, following " +
+			"sitemap indexes and gzipped sitemaps, with lastmod and priority, filterable by path prefix or date.",
+	}
+
+	mcp.AddTool(fs.mcpServer, sitemapTool, fs.handleSitemapTool)
+}
+
+// withCallTimeout applies a tool call's timeout parameter to the context; a nil
+// timeout leaves the context unchanged
+func withCallTimeout(ctx context.Context, timeout *int) (context.Context, context.CancelFunc, error) {
+	if timeout == nil {
+		return ctx, func() {}, nil
+	}
+	if *timeout <= 0 {
+		return nil, nil, fmt.Errorf("timeout must be a positive number of seconds")
+	}
+	ctx, cancel := context.WithTimeout(ctx, min(time.Duration(*timeout)*time.Second, maxCallTimeout))
+	return ctx, cancel, nil
 }
 
 // handleFetchTool processes fetch tool requests
 func (fs *FetchServer) handleFetchTool(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[FetchParams],
-) (*mcp.CallToolResultFor[any], error) {
+) (*mcp.CallToolResultFor[FetchOutput], error) {
 	log.Printf("Tool call received: fetch")
 
+	// Apply the per-call deadline; client cancellation also arrives through ctx
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	unit, err := processor.ParseUnit(params.Arguments.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := processor.ParsePageRanges(params.Arguments.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonPath *processor.JSONPath
+	if params.Arguments.JSONPath != "" {
+		if jsonPath, err = processor.ParseJSONPath(params.Arguments.JSONPath); err != nil {
+			return nil, err
+		}
+	}
+
+	selection, err := processor.ParseSelection(params.Arguments.Selector, params.Arguments.XPath, params.Arguments.ExcludeSelectors)
+	if err != nil {
+		return nil, err
+	}
+
+	since, err := parseSince(params.Arguments.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Arguments.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	cacheMode, err := fetcher.ParseCacheMode(params.Arguments.Cache)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to fetcher request
 	fetchReq := &fetcher.FetchRequest{
-		URL: params.Arguments.URL,
-		Raw: params.Arguments.Raw,
+		URL:           params.Arguments.URL,
+		Unit:          unit,
+		Raw:           params.Arguments.Raw,
+		BoundaryAware: params.Arguments.BoundaryAware,
+		Pages:         pages,
+		JSONPath:      jsonPath,
+		JSONShape:     params.Arguments.JSONShape,
+		Since:         since,
+		Limit:         params.Arguments.Limit,
+		DiscoverFeed:  params.Arguments.DiscoverFeed,
+		Metadata:      params.Arguments.Metadata,
+		AbsoluteURLs:  params.Arguments.AbsoluteURLs == nil || *params.Arguments.AbsoluteURLs,
+		Selection:     selection,
+		Section:       params.Arguments.Section,
+		Outline:       params.Arguments.Outline,
+		Cache:         cacheMode,
 	}
 
 	if params.Arguments.MaxLength != nil {
@@ -139,16 +438,66 @@ func (fs *FetchServer) handleFetchTool(
 	}
 
 	// Fetch the content
-	content, err := fs.fetcher.FetchURL(fetchReq)
+	result, err := fs.fetcher.Fetch(ctx, fetchReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return &mcp.CallToolResultFor[any]{
-		Content: []mcp.Content{&mcp.TextContent{Text: content}},
+	return &mcp.CallToolResultFor[FetchOutput]{
+		Meta: mcp.Meta{
+			"politeness_delay_ms": result.Delay.Milliseconds(),
+			"body_truncated":      result.Truncated,
+			"charset":             result.Charset,
+		},
+		Content:           []mcp.Content{&mcp.TextContent{Text: result.Content}},
+		StructuredContent: fetchOutput(result),
 	}, nil
 }
 
+// fetchOutput builds the structured tool result from a fetch result
+func fetchOutput(result *fetcher.FetchResult) FetchOutput {
+	output := FetchOutput{
+		FinalURL:      result.FinalURL,
+		Status:        result.Status,
+		ContentType:   result.ContentType,
+		Charset:       result.Charset,
+		Title:         result.Title,
+		Byline:        result.Byline,
+		PageCount:     result.PageCount,
+		Unit:          string(result.Page.Unit),
+		TotalLength:   result.Page.Total,
+		ReturnedRange: Range{Start: result.Page.Start, End: result.Page.End},
+		FetchedAt:     result.FetchedAt.UTC().Format(time.RFC3339),
+		FeedURL:       result.FeedURL,
+		CacheStatus:   result.CacheStatus,
+		Metadata:      result.Metadata,
+		Outline:       result.Outline,
+	}
+
+	if result.Published != nil {
+		output.Published = result.Published.UTC().Format(time.RFC3339)
+	}
+	if result.Page.Truncated {
+		next := result.Page.End
+		output.NextStartIndex = &next
+	}
+
+	return output
+}
+
+// parseSince parses the since parameter, an RFC 3339 time or a YYYY-MM-DD date in UTC
+func parseSince(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if since, err := time.Parse(layout, value); err == nil {
+			return &since, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid since %q: expected an RFC 3339 time or a YYYY-MM-DD date", value)
+}
+
 // Start starts the MCP server following the MCP specification
 func (fs *FetchServer) Start() error {
 	fs.logServerStartup()
@@ -231,7 +580,25 @@ func (fs *FetchServer) logServerStartup() {
 	if fs.config.ProxyURL != "" {
 		log.Printf("Using proxy: %s", fs.config.ProxyURL)
 	}
-	log.Printf("Available tools: fetch")
+	if fs.config.MaxBodySize > 0 {
+		log.Printf("Max body size: %d bytes (oversize policy: %s)", fs.config.MaxBodySize, fs.config.OversizePolicy)
+	} else {
+		log.Printf("Max body size: unlimited")
+	}
+	if len(fs.config.AllowedCIDRs) > 0 {
+		log.Printf("Allowed internal networks: %s", strings.Join(fs.config.AllowedCIDRs, ", "))
+	}
+	if fs.config.HTTPCacheSize > 0 {
+		log.Printf("HTTP cache: %d bytes in memory", fs.config.HTTPCacheSize)
+	}
+	if fs.config.HTTPCacheDir != "" {
+		log.Printf("HTTP cache directory: %s (%d bytes)", fs.config.HTTPCacheDir, fs.config.HTTPCacheDiskSize)
+	}
+	if fs.config.ContentCacheSize > 0 {
+		log.Printf("Content cache: %d bytes", fs.config.ContentCacheSize)
+	}
+	log.Printf("Batch workers: %d", fs.maxWorkers())
+	log.Printf("Available tools: fetch, fetch_many, crawl, extract_links, search_page, sitemap")
 
 	// Log endpoint based on transport
 	switch fs.config.Transport {
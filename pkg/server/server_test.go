@@ -2,12 +2,18 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/cache"
 	"github.com/stackloklabs/gofetch/pkg/config"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
 )
 
 func TestNewFetchServer(t *testing.T) {
@@ -18,10 +24,10 @@ func TestNewFetchServer(t *testing.T) {
 		Transport:    config.TransportSSE,
 	}
 
-	server := NewFetchServer(cfg)
+	server, err := NewFetchServer(cfg)
 
-	if server == nil {
-		t.Fatal("expected server to be created")
+	if err != nil || server == nil {
+		t.Fatalf("expected server to be created, got %v", err)
 	}
 	if server.config.Port != 8080 {
 		t.Errorf("expected port 8080, got %d", server.config.Port)
@@ -48,7 +54,7 @@ func TestNewFetchServerWithProxy(t *testing.T) {
 		Transport: config.TransportSSE,
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	if server == nil {
 		t.Fatal("expected server to be created")
@@ -58,6 +64,31 @@ func TestNewFetchServerWithProxy(t *testing.T) {
 	}
 }
 
+func TestNewFetchServerInvalidConfig(t *testing.T) {
+	configs := map[string]config.Config{
+		"allowed CIDR": {UserAgent: "test-agent", AllowedCIDRs: []string{"10.0.0.0/33"}},
+		"proxy URL":    {UserAgent: "test-agent", ProxyURL: "http://%zz"},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			if server, err := NewFetchServer(cfg); err == nil || server != nil {
+				t.Errorf("expected an invalid %s to be rejected, got %v", name, err)
+			}
+		})
+	}
+}
+
+// newTestServer creates a fetch server, failing the test if the configuration is rejected
+func newTestServer(tb testing.TB, cfg config.Config) *FetchServer {
+	tb.Helper()
+	server, err := NewFetchServer(cfg)
+	if err != nil {
+		tb.Fatalf("expected server to be created, got %v", err)
+	}
+	return server
+}
+
 func TestFetchParams(t *testing.T) {
 	maxLength := 1000
 	startIndex := 100
@@ -85,12 +116,13 @@ func TestFetchParams(t *testing.T) {
 
 func TestHandleFetchTool(t *testing.T) {
 	cfg := config.Config{
-		Port:      8080,
-		UserAgent: "test-agent",
-		Transport: config.TransportSSE,
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	// Create a test server to serve content
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -124,12 +156,13 @@ func TestHandleFetchTool(t *testing.T) {
 
 func TestHandleFetchToolWithParams(t *testing.T) {
 	cfg := config.Config{
-		Port:      8080,
-		UserAgent: "test-agent",
-		Transport: config.TransportSSE,
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	// Create a test server
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -166,6 +199,102 @@ func TestHandleFetchToolWithParams(t *testing.T) {
 	}
 }
 
+func TestHandleFetchToolBoundaryAware(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("First paragraph here.\n\nSecond paragraph is longer."))
+	}))
+	defer testServer.Close()
+
+	maxLength := 28
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL:           testServer.URL,
+			MaxLength:     &maxLength,
+			BoundaryAware: true,
+		},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	expected := "First paragraph here.\n\n[Content truncated. Use start_index=23 to get more content.]"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestHandleFetchToolStructuredContent(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Hello</title>
+<meta property="article:published_time" content="2024-03-01T10:00:00Z">
+<meta property="og:site_name" content="Example News"></head>
+<body><article><p>Some article text that is long enough to be kept by readability.</p></article></body></html>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	maxLength := 10
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL + "/old", MaxLength: &maxLength},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	output := result.StructuredContent
+	if output.FinalURL != testServer.URL+"/article" {
+		t.Errorf("expected final URL after redirect, got %q", output.FinalURL)
+	}
+	if output.Status != http.StatusOK || output.ContentType != "text/html; charset=utf-8" || output.Charset != "utf-8" {
+		t.Errorf("unexpected response details %+v", output)
+	}
+	if output.Title != "Hello" || output.Published != "2024-03-01T10:00:00Z" {
+		t.Errorf("expected article metadata, got title %q published %q", output.Title, output.Published)
+	}
+	if output.Unit != "chars" || output.ReturnedRange != (Range{Start: 0, End: 10}) || output.TotalLength <= 10 {
+		t.Errorf("unexpected range %+v of %d %s", output.ReturnedRange, output.TotalLength, output.Unit)
+	}
+	if output.NextStartIndex == nil || *output.NextStartIndex != 10 {
+		t.Errorf("expected next start index 10, got %v", output.NextStartIndex)
+	}
+	if _, err := time.Parse(time.RFC3339, output.FetchedAt); err != nil {
+		t.Errorf("expected RFC 3339 fetch time, got %q", output.FetchedAt)
+	}
+	if output.Metadata == nil || output.Metadata.SiteName != "Example News" ||
+		output.Metadata.OpenGraph["article:published_time"] != "2024-03-01T10:00:00Z" {
+		t.Errorf("expected page metadata, got %+v", output.Metadata)
+	}
+}
+
 func TestHandleFetchToolError(t *testing.T) {
 	cfg := config.Config{
 		Port:      8080,
@@ -173,7 +302,7 @@ func TestHandleFetchToolError(t *testing.T) {
 		Transport: config.TransportSSE,
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	ctx := context.Background()
 	params := &mcp.CallToolParamsFor[FetchParams]{
@@ -193,13 +322,524 @@ func TestHandleFetchToolError(t *testing.T) {
 	}
 }
 
+func TestHandleFetchToolInvalidPages(t *testing.T) {
+	server := newTestServer(t, config.Config{UserAgent: "test-agent", Transport: config.TransportSSE})
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: "https://example.com/paper.pdf", Pages: "3-1"},
+	}
+
+	_, err := server.handleFetchTool(context.Background(), nil, params)
+	if err == nil || !strings.Contains(err.Error(), "invalid page range") {
+		t.Errorf("expected invalid page range error, got %v", err)
+	}
+}
+
+func TestHandleFetchToolCache(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:     "test-agent",
+		Transport:     config.TransportSSE,
+		AllowedCIDRs:  []string{"127.0.0.0/8"},
+		HTTPCacheSize: 1024 * 1024,
+		HTTPCacheDir:  t.TempDir(),
+	}
+
+	server := newTestServer(t, cfg)
+
+	var requests atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("0123456789"))
+	}))
+	defer testServer.Close()
+
+	// Paging through the page fetches it once
+	maxLength := 5
+	for i, start := range []int{0, 5} {
+		params := &mcp.CallToolParamsFor[FetchParams]{
+			Name:      "fetch",
+			Arguments: FetchParams{URL: testServer.URL, MaxLength: &maxLength, StartIndex: &start},
+		}
+		result, err := server.handleFetchTool(context.Background(), nil, params)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		expected := []string{"miss", "hit"}[i]
+		if result.StructuredContent.CacheStatus != expected {
+			t.Errorf("expected a cache %s, got %q", expected, result.StructuredContent.CacheStatus)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", requests.Load())
+	}
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL + "/other", Cache: "only-if-cached"},
+	}
+	if _, err := server.handleFetchTool(context.Background(), nil, params); err == nil {
+		t.Error("expected only-if-cached to fail for a page never fetched")
+	}
+
+	params.Arguments.Cache = "reload"
+	if _, err := server.handleFetchTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an invalid cache mode to be rejected")
+	}
+}
+
+func TestNewHTTPCache(t *testing.T) {
+	if store := newHTTPCache(config.Config{}); store != nil {
+		t.Errorf("expected no cache without a size or directory, got %T", store)
+	}
+	if _, ok := newHTTPCache(config.Config{HTTPCacheSize: 1024}).(*cache.MemoryStore); !ok {
+		t.Error("expected a memory store")
+	}
+	store := newHTTPCache(config.Config{HTTPCacheSize: 1024, HTTPCacheDir: t.TempDir(), HTTPCacheDiskSize: 1024})
+	if _, ok := store.(*cache.TieredStore); !ok {
+		t.Errorf("expected a tiered store, got %T", store)
+	}
+}
+
+func TestHandleFetchToolJSONPath(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"items":[{"id":7,"name":"first"}]}}`))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL, JSONPath: "$.data.items[0]"},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "{\n  \"id\": 7,\n  \"name\": \"first\"\n}"
+	if text := result.Content[0].(*mcp.TextContent).Text; text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+
+	params.Arguments.JSONPath = "$.data["
+	if _, err := server.handleFetchTool(context.Background(), nil, params); err == nil {
+		t.Error("expected invalid json_path to be rejected")
+	}
+}
+
+func TestHandleFetchToolAbsoluteURLs(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/page", http.StatusFound)
+	})
+	mux.HandleFunc("/docs/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="next">Next page</a></body></html>`))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL + "/old"},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Links resolve against the final URL, after the redirect
+	expected := "[Next page](" + testServer.URL + "/docs/next)"
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, expected) {
+		t.Errorf("expected %q in %q", expected, text)
+	}
+
+	absolute := false
+	params.Arguments.AbsoluteURLs = &absolute
+	result, err = server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "[Next page](next)") {
+		t.Errorf("expected the relative link to be kept, got %q", text)
+	}
+}
+
+func TestHandleFetchToolSelector(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><div class="post"><p>First post</p><span class="meta">1 day ago</span></div>
+<div class="post"><p>Second post</p></div></body></html>`))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL, Selector: ".post", ExcludeSelectors: []string{".meta"}},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; text != "First post\n\n---\n\nSecond post" {
+		t.Errorf("expected both posts without their metadata, got %q", text)
+	}
+
+	params.Arguments.XPath = "//p"
+	if _, err := server.handleFetchTool(context.Background(), nil, params); err == nil {
+		t.Error("expected selector and xpath together to be rejected")
+	}
+}
+
+func TestHandleFetchToolOutline(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/markdown")
+		w.Write([]byte("# Docs\n\n## Configuration\n\n### Proxy\n\nSet proxy_url.\n"))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL, Section: "Configuration", Outline: true},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	outline := result.StructuredContent.Outline
+	if len(outline) != 2 || outline[0].Offset != 0 || outline[1].Anchor != "proxy" {
+		t.Errorf("expected the outline of the configuration section, got %+v", outline)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{value: "", expected: ""},
+		{value: "2024-03-01", expected: "2024-03-01T00:00:00Z"},
+		{value: "2024-03-01T10:00:00+02:00", expected: "2024-03-01T08:00:00Z"},
+		{value: "last week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		since, err := parseSince(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSince(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		got := ""
+		if since != nil {
+			got = since.UTC().Format(time.RFC3339)
+		}
+		if got != tt.expected {
+			t.Errorf("parseSince(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleFetchToolWithoutBodySizeLimit(t *testing.T) {
+	cfg := config.Config{
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+		MaxBodySize:  0,
+	}
+
+	server := newTestServer(t, cfg)
+
+	body := strings.Repeat("a", fetcher.DefaultMaxBodySize+1)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL: testServer.URL,
+		},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+
+	if err != nil {
+		t.Fatalf("expected a zero limit to disable the size check, got %v", err)
+	}
+	if result.StructuredContent.TotalLength != len(body) {
+		t.Errorf("expected the whole body, got %d characters", result.StructuredContent.TotalLength)
+	}
+}
+
+func TestHandleFetchToolBlocksLoopback(t *testing.T) {
+	cfg := config.Config{
+		Port:      8080,
+		UserAgent: "test-agent",
+		Transport: config.TransportSSE,
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><h1>Internal</h1></body></html>"))
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL: testServer.URL,
+		},
+	}
+
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+
+	if err == nil {
+		t.Fatal("expected loopback fetch to be blocked")
+	}
+	if !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected blocked address error, got %v", err)
+	}
+	if result != nil {
+		t.Error("expected no result on error")
+	}
+}
+
+func TestHandleFetchToolBlocksLoopbackThroughProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		proxied.Add(1)
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	cfg := config.Config{
+		Port:      8080,
+		UserAgent: "test-agent",
+		ProxyURL:  proxy.URL,
+		Transport: config.TransportSSE,
+	}
+
+	server := newTestServer(t, cfg)
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL: "http://127.0.0.1:9/internal",
+		},
+	}
+
+	_, err := server.handleFetchTool(context.Background(), nil, params)
+
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected blocked address error, got %v", err)
+	}
+	if proxied.Load() != 0 {
+		t.Errorf("expected the proxy not to be contacted, got %d requests", proxied.Load())
+	}
+}
+
+func TestHandleFetchToolEnvironmentProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		proxied.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "")
+	server := newTestServer(t, config.Config{UserAgent: "test-agent", Transport: config.TransportSSE})
+
+	// The loopback proxy itself may be reached, for a public destination
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: "http://93.184.216.34/page"},
+	}
+	result, err := server.handleFetchTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected the public destination to be fetched through the proxy, got %v", err)
+	}
+	if result.StructuredContent.TotalLength != len("proxied") || proxied.Load() != 1 {
+		t.Errorf("expected the proxy to serve the page, got %d requests", proxied.Load())
+	}
+
+	// An internal destination is blocked before reaching the proxy
+	params.Arguments.URL = "http://169.254.169.254/latest/meta-data/"
+	_, err = server.handleFetchTool(context.Background(), nil, params)
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected blocked address error, got %v", err)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("expected the proxy not to be contacted again, got %d requests", proxied.Load())
+	}
+}
+
+func TestHandleFetchToolBlocksRedirectToInternalAddress(t *testing.T) {
+	cfg := config.Config{
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.1/32"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	// The first hop is allowlisted, but the redirect target is not and must be
+	// rejected when its connection is dialed
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "http://127.0.0.2:1/internal", http.StatusFound)
+	}))
+	defer testServer.Close()
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL: testServer.URL + "/redirect",
+		},
+	}
+
+	_, err := server.handleFetchTool(context.Background(), nil, params)
+
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected redirect to internal address to be blocked, got %v", err)
+	}
+}
+
+func TestHandleFetchToolTimeout(t *testing.T) {
+	cfg := config.Config{
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		IgnoreRobots: true,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		w.Write([]byte("too late"))
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	timeout := 1
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name: "fetch",
+		Arguments: FetchParams{
+			URL:     testServer.URL,
+			Timeout: &timeout,
+		},
+	}
+
+	start := time.Now()
+	_, err := server.handleFetchTool(context.Background(), nil, params)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected call to stop at its deadline, took %s", elapsed)
+	}
+}
+
+func TestHandleFetchToolCancelled(t *testing.T) {
+	cfg := config.Config{
+		Port:         8080,
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	params := &mcp.CallToolParamsFor[FetchParams]{
+		Name:      "fetch",
+		Arguments: FetchParams{URL: testServer.URL},
+	}
+
+	_, err := server.handleFetchTool(ctx, nil, params)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
 func TestStartUnsupportedTransport(t *testing.T) {
 	cfg := config.Config{
 		Port:      8080,
 		Transport: "invalid-transport",
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 	err := server.Start()
 
 	if err == nil {
@@ -210,7 +850,7 @@ func TestStartUnsupportedTransport(t *testing.T) {
 	}
 }
 
-func TestLogServerStartup(_ *testing.T) {
+func TestLogServerStartup(t *testing.T) {
 	cfg := config.Config{
 		Port:         9090,
 		UserAgent:    "test-agent",
@@ -219,20 +859,20 @@ func TestLogServerStartup(_ *testing.T) {
 		Transport:    config.TransportSSE,
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	// This test just ensures logServerStartup doesn't panic
 	// In a real test environment, you might want to capture logs
 	server.logServerStartup()
 }
 
-func TestLogServerStartupStreamableHTTP(_ *testing.T) {
+func TestLogServerStartupStreamableHTTP(t *testing.T) {
 	cfg := config.Config{
 		Port:      8080,
 		Transport: config.TransportStreamableHTTP,
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 	server.logServerStartup()
 }
 
@@ -245,7 +885,7 @@ func TestConfigFieldsPreserved(t *testing.T) {
 		Transport:    config.TransportStreamableHTTP,
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(t, cfg)
 
 	if server.config.Port != cfg.Port {
 		t.Errorf("expected port %d, got %d", cfg.Port, server.config.Port)
@@ -273,18 +913,19 @@ func BenchmarkNewFetchServer(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = NewFetchServer(cfg)
+		_ = newTestServer(b, cfg)
 	}
 }
 
 func BenchmarkHandleFetchTool(b *testing.B) {
 	cfg := config.Config{
-		Port:      8080,
-		UserAgent: "benchmark-agent",
-		Transport: config.TransportSSE,
+		Port:         8080,
+		UserAgent:    "benchmark-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
 	}
 
-	server := NewFetchServer(cfg)
+	server := newTestServer(b, cfg)
 
 	// Create a test server
 	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
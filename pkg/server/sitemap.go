@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/fetcher"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+// sitemap tool limits
+const (
+	defaultSitemapLimit = 200
+	maxSitemapLimit     = 5000
+)
+
+// SitemapParams defines the input parameters for the sitemap tool
+type SitemapParams struct {
+	URL        string `json:"url" mcp:"Sitemap URL, or any URL of a site whose sitemaps are found from robots.txt or /sitemap.xml"`
+	PathPrefix string `json:"path_prefix,omitempty" mcp:"Only list URLs whose path starts with this prefix"`
+	Since      string `json:"since,omitempty" mcp:"Only list URLs modified since this RFC 3339 time or YYYY-MM-DD date"`
+	Limit      *int   `json:"limit,omitempty" mcp:"Maximum number of URLs to return (default: 200, max: 5000)"`
+	Timeout    *int   `json:"timeout,omitempty" mcp:"Maximum number of seconds to spend on this call"`
+}
+
+// SitemapOutput is the structured result of the sitemap tool
+type SitemapOutput struct {
+	Sitemaps []string               `json:"sitemaps" mcp:"Sitemaps that were read, including indexes"`
+	URLs     []processor.SitemapURL `json:"urls" mcp:"Matching URLs with their lastmod, changefreq and priority"`
+	Total    int                    `json:"total" mcp:"Number of matching URLs, including those not returned"`
+	Errors   []string               `json:"errors,omitempty" mcp:"Sitemaps that could not be read"`
+}
+
+// handleSitemapTool processes sitemap tool requests
+func (fs *FetchServer) handleSitemapTool(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[SitemapParams],
+) (*mcp.CallToolResultFor[SitemapOutput], error) {
+	log.Printf("Tool call received: sitemap")
+
+	ctx, cancel, err := withCallTimeout(ctx, params.Arguments.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	args := params.Arguments
+	since, err := parseSince(args.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := defaultSitemapLimit
+	if args.Limit != nil {
+		if *args.Limit <= 0 {
+			return nil, fmt.Errorf("limit must be positive")
+		}
+		limit = min(*args.Limit, maxSitemapLimit)
+	}
+
+	result, err := fs.fetcher.Sitemap(ctx, &fetcher.SitemapRequest{
+		URL:        args.URL,
+		PathPrefix: args.PathPrefix,
+		Since:      since,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := SitemapOutput{
+		Sitemaps: result.Sitemaps,
+		URLs:     result.URLs,
+		Total:    result.Total,
+		Errors:   result.Errors,
+	}
+	if output.URLs == nil {
+		output.URLs = []processor.SitemapURL{}
+	}
+
+	return &mcp.CallToolResultFor[SitemapOutput]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: formatSitemap(args.URL, output)}},
+		StructuredContent: output,
+	}, nil
+}
+
+// formatSitemap lists sitemap URLs with their lastmod and priority, followed
+// by the sitemaps that failed
+func formatSitemap(target string, output SitemapOutput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d URLs in %d sitemaps for %s", output.Total, len(output.Sitemaps), target)
+	if len(output.URLs) < output.Total {
+		fmt.Fprintf(&b, ", showing the first %d", len(output.URLs))
+	}
+	b.WriteString("\n")
+
+	for _, entry := range output.URLs {
+		fmt.Fprintf(&b, "\n- %s", entry.URL)
+		if entry.LastMod != "" {
+			fmt.Fprintf(&b, " · lastmod %s", entry.LastMod)
+		}
+		if entry.Priority != nil {
+			fmt.Fprintf(&b, " · priority %s", strconv.FormatFloat(*entry.Priority, 'f', -1, 64))
+		}
+	}
+
+	if len(output.Errors) > 0 {
+		b.WriteString("\n\nSitemaps that could not be read:\n")
+		for _, failure := range output.Errors {
+			fmt.Fprintf(&b, "\n- %s", failure)
+		}
+	}
+
+	return b.String()
+}
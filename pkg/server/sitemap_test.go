@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stackloklabs/gofetch/pkg/config"
+	"github.com/stackloklabs/gofetch/pkg/processor"
+)
+
+func TestHandleSitemapTool(t *testing.T) {
+	cfg := config.Config{
+		UserAgent:    "test-agent",
+		Transport:    config.TransportSSE,
+		AllowedCIDRs: []string{"127.0.0.0/8"},
+	}
+
+	server := newTestServer(t, cfg)
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>http://%[1]s/docs/a</loc><lastmod>2024-05-01</lastmod><priority>0.5</priority></url>
+<url><loc>http://%[1]s/docs/b</loc><lastmod>2023-05-01</lastmod></url>
+<url><loc>http://%[1]s/blog/c</loc><lastmod>2024-06-01</lastmod></url>
+</urlset>`, r.Host)
+	}))
+	defer testServer.Close()
+
+	limit := 1
+	params := &mcp.CallToolParamsFor[SitemapParams]{
+		Name:      "sitemap",
+		Arguments: SitemapParams{URL: testServer.URL + "/", PathPrefix: "/docs/", Limit: &limit},
+	}
+
+	result, err := server.handleSitemapTool(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	output := result.StructuredContent
+	if output.Total != 2 || len(output.URLs) != 1 || output.URLs[0].URL != testServer.URL+"/docs/a" {
+		t.Errorf("unexpected output %+v", output)
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	if !strings.Contains(text, "showing the first 1") || !strings.Contains(text, "/docs/a · lastmod 2024-05-01 · priority 0.5") {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	params.Arguments = SitemapParams{URL: testServer.URL + "/sitemap.xml", Since: "2024-01-01"}
+	result, err = server.handleSitemapTool(context.Background(), nil, params)
+	if err != nil || result.StructuredContent.Total != 2 {
+		t.Errorf("expected two URLs modified since 2024, got %+v, %v", result, err)
+	}
+
+	params.Arguments.Since = "yesterday"
+	if _, err := server.handleSitemapTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an invalid since to be rejected")
+	}
+
+	params.Arguments = SitemapParams{URL: testServer.URL + "/other.xml"}
+	if _, err := server.handleSitemapTool(context.Background(), nil, params); err == nil {
+		t.Error("expected an error when no sitemap can be read")
+	}
+}
+
+func TestFormatSitemap(t *testing.T) {
+	priority := 0.8
+	text := formatSitemap("https://example.com/", SitemapOutput{
+		Sitemaps: []string{"https://example.com/sitemap.xml"},
+		URLs:     []processor.SitemapURL{{URL: "https://example.com/a", LastMod: "2024-05-01", Priority: &priority}},
+		Total:    1,
+		Errors:   []string{"https://example.com/b.xml: HTTP 404: 404 Not Found"},
+	})
+
+	expected := "Found 1 URLs in 1 sitemaps for https://example.com/\n\n" +
+		"- https://example.com/a · lastmod 2024-05-01 · priority 0.8\n\n" +
+		"Sitemaps that could not be read:\n\n- https://example.com/b.xml: HTTP 404: 404 Not Found"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}